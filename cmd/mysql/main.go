@@ -2,40 +2,42 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
-	"time"
+
+	"github.com/solontsev/rdbms-performance-comparison/harness"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
-var db *sql.DB // Database connection pool.
-
 var host = "localhost"
 var port = 3306
 var user = "root"
 var password = "mysql"
 var dbname = "TEST"
 
-func main() {
-	// Build connection string
-	psqlConnString := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s",
-		user, password, host, port, dbname)
-
-	var err error
+func dsn() string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", user, password, host, port, dbname)
+}
 
-	db, err = sql.Open("mysql", psqlConnString)
-	if err != nil {
-		log.Fatal("Error creating connection: ", err.Error())
-	}
-	defer db.Close()
+var queryFlag = flag.String("query", "q4", "the query to measure, q1-q4, all of them when empty")
+
+// suite are the queries on the q1-q4 tables.
+var suite = harness.Suite{
+	Name: "mysql",
+	Queries: []harness.Query{
+		{Name: "q1", SQL: "select count(*) from q1 as p where status_id = ?;", Args: []any{1}},
+		{Name: "q2", SQL: "select count(*) from q2 as p where status = ?;", Args: []any{"active"}},
+		{Name: "q3", SQL: "select count(*) from q3 as p where status = ?;", Args: []any{"active"}},
+		{Name: "q4", SQL: "select count(*) from q4 as p where status = ?;", Args: []any{"active"}},
+	},
+}
 
-	db.SetConnMaxLifetime(0)
-	db.SetMaxIdleConns(3)
-	db.SetMaxOpenConns(3)
+func main() {
+	flag.Parse()
 
 	ctx, stop := context.WithCancel(context.Background())
 	defer stop()
@@ -48,83 +50,15 @@ func main() {
 		stop()
 	}()
 
-	warmUpExecutions := 5
-	testExecutions := 20
-
-	Ping(ctx)
-
-	for i := 0; i < warmUpExecutions; i++ {
-		Query4(ctx, "active")
-	}
-
-	start := time.Now()
-
-	for i := 0; i < testExecutions; i++ {
-		Query4(ctx, "active")
-	}
-
-	elapsed := time.Since(start)
-
-	log.Printf("Avg execution time: %s", elapsed/time.Duration(testExecutions))
-}
-
-// Ping the database to verify DSN provided by the user is valid and the
-// server accessible. If the ping fails exit the program with an error.
-func Ping(ctx context.Context) {
-	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
-	defer cancel()
-
-	if err := db.PingContext(ctx); err != nil {
-		log.Fatalf("Unable to connect to database: %v", err)
-	}
-}
-
-// Query1 the database for the information requested and prints the results.
-// If the query fails exit the program with an error.
-func Query1(ctx context.Context, status_id int32) {
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	var result int32
-	err := db.QueryRowContext(ctx, "select count(*) from q1 as p where status_id = ?;", status_id).Scan(&result)
-	if err != nil {
-		log.Fatal("unable to execute search query", err)
-	}
-	log.Println("result = ", result)
-}
-
-func Query2(ctx context.Context, status string) {
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	var result int32
-	err := db.QueryRowContext(ctx, "select count(*) from q2 as p where status = ?;", status).Scan(&result)
+	s, err := suite.Select(*queryFlag)
 	if err != nil {
-		log.Fatal("unable to execute search query", err)
+		log.Fatal(err)
 	}
-	log.Println("result = ", result)
-}
-
-func Query3(ctx context.Context, status string) {
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
-
-	var result int32
-	err := db.QueryRowContext(ctx, "select count(*) from q3 as p where status = ?;", status).Scan(&result)
-	if err != nil {
-		log.Fatal("unable to execute search query", err)
-	}
-	log.Println("result = ", result)
-}
-
-func Query4(ctx context.Context, status string) {
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
 
-	var result int32
-	err := db.QueryRowContext(ctx, "select count(*) from q4 as p where status = ?;", status).Scan(&result)
+	target := harness.Target{Name: "mysql", Driver: "mysql", DSN: dsn()}
+	results, err := harness.NewRunner().Run(ctx, target, s, nil)
 	if err != nil {
-		log.Fatal("unable to execute search query", err)
+		log.Fatal("unable to execute search query: ", err)
 	}
-	log.Println("result = ", result)
+	harness.LogResults(results)
 }
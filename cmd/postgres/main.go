@@ -2,41 +2,44 @@ package main
 
 import (
 	"context"
-	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
-	"time"
+
+	"github.com/solontsev/rdbms-performance-comparison/harness"
 
 	_ "github.com/lib/pq"
 )
 
-var db *sql.DB // Database connection pool.
-
 var host = "localhost"
 var port = 54320
 var user = "postgres"
 var password = "postgres"
 var dbname = "q1"
 
-func main() {
-	// Build connection string
-	psqlConnString := fmt.Sprintf("host=%s port=%d user=%s "+
+func dsn() string {
+	return fmt.Sprintf("host=%s port=%d user=%s "+
 		"password=%s dbname=%s sslmode=disable",
 		host, port, user, password, dbname)
+}
 
-	var err error
-
-	db, err = sql.Open("postgres", psqlConnString)
-	if err != nil {
-		log.Fatal("Error creating connection: ", err.Error())
-	}
-	defer db.Close()
+var queryFlag = flag.String("query", "q1", "the query to measure, q1-q4, all of them when empty")
+
+// suite are the queries on the q1-q4 tables.
+var suite = harness.Suite{
+	Name: "postgres",
+	Queries: []harness.Query{
+		{Name: "q1", SQL: "select count(*) from public.q1 as p where status_id = $1;", Args: []any{1}},
+		{Name: "q2", SQL: "select count(*) from public.q2 as p where status = $1;", Args: []any{"active"}},
+		{Name: "q3", SQL: "select count(*) from public.q3 as p where status = $1;", Args: []any{"active"}},
+		{Name: "q4", SQL: "select count(*) from public.q4 as p where status = $1;", Args: []any{"active"}},
+	},
+}
 
-	db.SetConnMaxLifetime(0)
-	db.SetMaxIdleConns(3)
-	db.SetMaxOpenConns(3)
+func main() {
+	flag.Parse()
 
 	ctx, stop := context.WithCancel(context.Background())
 	defer stop()
@@ -49,83 +52,15 @@ func main() {
 		stop()
 	}()
 
-	warmUpExecutions := 5
-	testExecutions := 20
-
-	Ping(ctx)
-
-	for i := 0; i < warmUpExecutions; i++ {
-		Query1(ctx, 1)
-	}
-
-	start := time.Now()
-
-	for i := 0; i < testExecutions; i++ {
-		Query1(ctx, 1)
-	}
-
-	elapsed := time.Since(start)
-
-	log.Printf("Avg execution time: %s", elapsed/time.Duration(testExecutions))
-}
-
-// Ping the database to verify DSN provided by the user is valid and the
-// server accessible. If the ping fails exit the program with an error.
-func Ping(ctx context.Context) {
-	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
-	defer cancel()
-
-	if err := db.PingContext(ctx); err != nil {
-		log.Fatalf("Unable to connect to database: %v", err)
-	}
-}
-
-// Query1 the database for the information requested and prints the results.
-// If the query fails exit the program with an error.
-func Query1(ctx context.Context, status_id int32) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	var result int32
-	err := db.QueryRowContext(ctx, "select count(*) from public.q1 as p where status_id = $1;", sql.Named("status_id", status_id)).Scan(&result)
-	if err != nil {
-		log.Fatal("unable to execute search query", err)
-	}
-	log.Println("result = ", result)
-}
-
-func Query2(ctx context.Context, status string) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	var result int32
-	err := db.QueryRowContext(ctx, "select count(*) from public.q2 as p where status = $1;", sql.Named("status", status)).Scan(&result)
+	s, err := suite.Select(*queryFlag)
 	if err != nil {
-		log.Fatal("unable to execute search query", err)
+		log.Fatal(err)
 	}
-	log.Println("result = ", result)
-}
-
-func Query3(ctx context.Context, status string) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	var result int32
-	err := db.QueryRowContext(ctx, "select count(*) from public.q3 as p where status = $1;", sql.Named("status", status)).Scan(&result)
-	if err != nil {
-		log.Fatal("unable to execute search query", err)
-	}
-	log.Println("result = ", result)
-}
-
-func Query4(ctx context.Context, status string) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
 
-	var result int32
-	err := db.QueryRowContext(ctx, "select count(*) from public.q4 as p where status = $1;", sql.Named("status", status)).Scan(&result)
+	target := harness.Target{Name: "postgres", Driver: "postgres", DSN: dsn()}
+	results, err := harness.NewRunner().Run(ctx, target, s, nil)
 	if err != nil {
-		log.Fatal("unable to execute search query", err)
+		log.Fatal("unable to execute search query: ", err)
 	}
-	log.Println("result = ", result)
+	harness.LogResults(results)
 }
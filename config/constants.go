@@ -3,5 +3,8 @@ package config
 import "time"
 
 const ContainerStartupTimeout = time.Second * 120
+const PingTimeout = time.Second * 5
+const QueryTimeout = time.Minute * 5
+const MaxConnections = 3
 const WarmUpExecutions = 5
 const TestExecutions = 20
@@ -0,0 +1,183 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+	"net"
+	"net/url"
+	"strings"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/go-sql-driver/mysql"
+	"github.com/solontsev/rdbms-performance-comparison/harness"
+	"github.com/testcontainers/testcontainers-go"
+)
+
+var containersFlag = flag.Bool("containers", false, "start a container for every target, load the fixtures, run the test and remove the containers")
+
+// the port every engine listens on inside its container
+var enginePorts = map[string]nat.Port{
+	engineMariaDb:  "3306/tcp",
+	engineMySql:    "3306/tcp",
+	enginePostgres: "5432/tcp",
+	engineMsSql:    "1433/tcp",
+}
+
+// credentials returns the user, password and database of the DSN of a target.
+func credentials(t target) (string, string, string, error) {
+	if t.driverName == "mysql" {
+		cfg, err := mysql.ParseDSN(t.dsn)
+		if err != nil {
+			return "", "", "", err
+		}
+		return cfg.User, cfg.Passwd, cfg.DBName, nil
+	}
+
+	u, err := url.Parse(t.dsn)
+	if err != nil {
+		return "", "", "", err
+	}
+	password, _ := u.User.Password()
+	database := strings.TrimPrefix(u.Path, "/")
+	if t.driverName == "sqlserver" {
+		database = u.Query().Get("database")
+	}
+	return u.User.Username(), password, database, nil
+}
+
+// withAddress returns the target connected to another host and port.
+func withAddress(t target, host string, port string) (target, error) {
+	address := net.JoinHostPort(host, port)
+	if t.driverName == "mysql" {
+		cfg, err := mysql.ParseDSN(t.dsn)
+		if err != nil {
+			return t, err
+		}
+		cfg.Addr = address
+		t.dsn = cfg.FormatDSN()
+		return t, nil
+	}
+
+	u, err := url.Parse(t.dsn)
+	if err != nil {
+		return t, err
+	}
+	u.Host = address
+	t.dsn = u.String()
+	return t, nil
+}
+
+// containerEnv configures the image of a target to accept the credentials of
+// its DSN. MSSQL has no setting for the database, setup creates it.
+func containerEnv(t target) (map[string]string, error) {
+	user, password, database, err := credentials(t)
+	if err != nil {
+		return nil, err
+	}
+
+	switch t.engine {
+	case engineMsSql:
+		if !strings.EqualFold(user, "sa") {
+			return nil, fmt.Errorf("the MSSQL image only sets the password of SA, not of %s", user)
+		}
+		return map[string]string{"ACCEPT_EULA": "Y", "MSSQL_SA_PASSWORD": password, "MSSQL_PID": "Developer"}, nil
+	case engineMySql, engineMariaDb:
+		prefix := "MYSQL_"
+		if t.engine == engineMariaDb {
+			prefix = "MARIADB_"
+		}
+		if user != "root" {
+			// the setup needs root to enable LOAD DATA LOCAL
+			return nil, fmt.Errorf("the setup connects as root, not as %s", user)
+		}
+		return map[string]string{prefix + "ROOT_PASSWORD": password, prefix + "DATABASE": database}, nil
+	default:
+		return map[string]string{"POSTGRES_USER": user, "POSTGRES_PASSWORD": password, "POSTGRES_DB": database}, nil
+	}
+}
+
+// startContainer starts the image of a target, waits until the server
+// accepts connections and returns the target connected to it.
+func startContainer(ctx context.Context, t target, limits harness.Resources) (testcontainers.Container, target, error) {
+	if t.image == "" {
+		return nil, t, fmt.Errorf("there is no image in the config")
+	}
+	port, ok := enginePorts[t.engine]
+	if !ok {
+		return nil, t, fmt.Errorf("unknown engine %s", t.engine)
+	}
+	env, err := containerEnv(t)
+	if err != nil {
+		return nil, t, err
+	}
+
+	// the database of the target does not exist on MSSQL until setup creates it
+	maintenance, _, err := withDatabase(t, maintenanceDatabase(t.engine))
+	if err != nil {
+		return nil, t, err
+	}
+	waitDsn := func(host string, port nat.Port) string {
+		m, err := withAddress(maintenance, host, port.Port())
+		if err != nil {
+			fatalf("Invalid DSN of %s: %v", t.connectionName, err)
+		}
+		return m.dsn
+	}
+
+	req := testcontainers.ContainerRequest{
+		Image: t.image,
+		Env:   env,
+		Cmd:   profileArgs(t),
+	}
+	if t.engine == engineMsSql {
+		// there is no arm64 image of MSSQL, the other engines run natively
+		req.ImagePlatform = "linux/amd64"
+	}
+	if err := storageRequest(&req, t, *dataDirFlag); err != nil {
+		return nil, t, err
+	}
+	container, err := harness.StartContainer(ctx, req, limits, t.driverName, port, waitDsn)
+	if container == nil {
+		return nil, t, err
+	}
+	if err != nil {
+		return container, t, err
+	}
+
+	t.container = container.GetContainerID()
+	t, err = withAddress(t, container.ServerHost, container.ServerPort.Port())
+	return container, t, err
+}
+
+// startContainers starts a container for every target with the same resource
+// limits and points the targets to them. The returned function removes the containers, the reaper
+// of testcontainers removes them when the runner exits without calling it.
+func startContainers(ctx context.Context, limits harness.Resources) func() {
+	var containers []testcontainers.Container
+	terminate := func() {
+		for _, c := range containers {
+			if err := c.Terminate(context.Background()); err != nil {
+				log.Printf("unable to remove container %s: %v", c.GetContainerID(), err)
+			}
+		}
+	}
+
+	for i, d := range databases {
+		log.Printf("%s: starting %s, %s...", d.connectionName, d.image, limits)
+		if warning := startWarning(limits, d); warning != "" {
+			log.Printf("%s: warning: %s", d.connectionName, warning)
+		}
+		container, started, err := startContainer(ctx, d, limits)
+		if container != nil {
+			containers = append(containers, container)
+		}
+		if err != nil {
+			terminate()
+			fatalf("%s: unable to start the container: %v", d.connectionName, err)
+		}
+		databases[i] = started
+	}
+	return terminate
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"math/rand"
+)
+
+// distribution generates the value of a column. row holds the values of the
+// columns generated before it, the id first, so columns can depend on each
+// other.
+type distribution func(id int, rnd *rand.Rand, row []any) any
+
+// uniform spreads the values evenly over [min, max].
+func uniform(min int, max int) distribution {
+	return func(_ int, rnd *rand.Rand, _ []any) any {
+		return min + rnd.Intn(max-min+1)
+	}
+}
+
+// zipf skews the values over [1, n], value k is about 1/k^s as frequent as
+// value 1. s has to be greater than 1.
+func zipf(s float64, n int) distribution {
+	var source *rand.Rand
+	var z *rand.Zipf
+	return func(_ int, rnd *rand.Rand, _ []any) any {
+		// the generator is bound to the random source of the table being generated
+		if source != rnd {
+			source, z = rnd, rand.NewZipf(rnd, s, 1, uint64(n-1))
+		}
+		return int(z.Uint64()) + 1
+	}
+}
+
+// correlated copies the value of an earlier column with the given
+// probability and falls back to another distribution otherwise, so the
+// columns are not independent as optimizers usually assume.
+func correlated(column int, probability float64, fallback distribution) distribution {
+	return func(id int, rnd *rand.Rand, row []any) any {
+		if rnd.Float64() < probability {
+			return row[column]
+		}
+		return fallback(id, rnd, row)
+	}
+}
+
+// withNulls replaces the given fraction of the values with NULL.
+func withNulls(fraction float64, d distribution) distribution {
+	return func(id int, rnd *rand.Rand, row []any) any {
+		if rnd.Float64() < fraction {
+			return nil
+		}
+		return d(id, rnd, row)
+	}
+}
+
+// hotRange puts the given fraction of the values into [min, max].
+func hotRange(min int, max int, fraction float64, d distribution) distribution {
+	hot := uniform(min, max)
+	return func(id int, rnd *rand.Rand, row []any) any {
+		if rnd.Float64() < fraction {
+			return hot(id, rnd, row)
+		}
+		return d(id, rnd, row)
+	}
+}
+
+// labels maps the integers [1, len(names)] of another distribution to names.
+func labels(names []string, d distribution) distribution {
+	return func(id int, rnd *rand.Rand, row []any) any {
+		v := d(id, rnd, row)
+		if v == nil {
+			return nil
+		}
+		return names[(v.(int)-1)%len(names)]
+	}
+}
+
+// profile generates rows with ids starting at 1 and a column for every
+// distribution.
+func profile(columns ...distribution) func(int, *rand.Rand, func(...any)) {
+	return sequence(1, func(id int, rnd *rand.Rand) []any {
+		row := make([]any, 1, len(columns)+1)
+		row[0] = id
+		for _, d := range columns {
+			row = append(row, d(id, rnd, row))
+		}
+		return row
+	})
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+)
+
+// frequencies generates n rows of a profile and counts the values of a column.
+func frequencies(n int, column int, columns ...distribution) map[any]int {
+	counts := make(map[any]int)
+	profile(columns...)(n, rand.New(rand.NewSource(1)), func(row ...any) {
+		counts[row[column]]++
+	})
+	return counts
+}
+
+func TestDistributions(t *testing.T) {
+	const n = 100000
+	near := func(actual int, expected float64) bool {
+		return math.Abs(float64(actual)-expected) < expected*0.1
+	}
+
+	if counts := frequencies(n, 1, withNulls(0.3, uniform(1, 10))); !near(counts[nil], 0.3*n) {
+		t.Errorf("withNulls(0.3) generated %d NULLs of %d", counts[nil], n)
+	}
+
+	hot := 0
+	for v, count := range frequencies(n, 1, hotRange(1, 10, 0.8, uniform(1, 100000))) {
+		if v.(int) <= 10 {
+			hot += count
+		}
+	}
+	if !near(hot, 0.8*n) {
+		t.Errorf("hotRange(0.8) generated %d hot values of %d", hot, n)
+	}
+
+	counts := frequencies(n, 1, zipf(1.2, 1000))
+	if counts[1] < 10*counts[10] || counts[1] < n/10 {
+		t.Errorf("zipf is not skewed, %d ones and %d tens", counts[1], counts[10])
+	}
+
+	same := 0
+	profile(uniform(1, 1000), correlated(1, 0.9, uniform(1, 1000)))(n, rand.New(rand.NewSource(1)), func(row ...any) {
+		if row[1] == row[2] {
+			same++
+		}
+	})
+	if !near(same, 0.9*n) {
+		t.Errorf("correlated(0.9) copied %d values of %d", same, n)
+	}
+
+	names := frequencies(n, 1, labels([]string{"a", "b"}, uniform(1, 2)))
+	if len(names) != 2 || names["a"]+names["b"] != n {
+		t.Errorf("labels generated %v", names)
+	}
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/solontsev/rdbms-performance-comparison/harness"
+)
+
+// dmlTest makes a test a DML test. Its queries modify data, the rows they affect
+// are checked against the row count in the query name, e.g. "a - 1,000 rows".
+type dmlTest struct {
+	// rollback runs every execution in a transaction that is rolled back,
+	// otherwise every execution commits and the reset statements restore the data
+	rollback bool
+	// reset statements by target (or engine) and query, they run after every execution
+	reset map[string]map[string]string
+}
+
+// resetStatements returns the statements that restore the data after an
+// execution of a query of a DML test.
+func resetStatements(test testData, t target, queryName string, sf float64) []string {
+	if test.dml == nil {
+		return nil
+	}
+	for _, key := range t.queryKeys() {
+		if reset, ok := test.dml.reset[key]; ok {
+			if statement, ok := reset[queryName]; ok {
+				return []string{scaleQuery(statement, sf)}
+			}
+			return nil
+		}
+	}
+	return nil
+}
+
+// execDml measures the statement of a DML variant. The rollback of the
+// transaction, the cleanup and the session policy are not measured. The cell
+// reports the rows the statement affected.
+func execDml(ctx context.Context, s *session, test testData, v variant, execs int) cell {
+	var tx *sql.Tx
+	begin := func() {
+		if !test.dml.rollback {
+			return
+		}
+		var err error
+		if tx, err = s.conn.BeginTx(ctx, nil); err != nil {
+			fatalf("unable to begin a transaction: %v", err)
+		}
+	}
+
+	affected := make(map[int64]struct{})
+	exec := func(ctx context.Context) error {
+		var result sql.Result
+		var err error
+		if tx != nil {
+			result, err = tx.ExecContext(ctx, v.query)
+		} else {
+			result, err = s.conn.ExecContext(ctx, v.query)
+		}
+		if err != nil {
+			return err
+		}
+		rows, err := result.RowsAffected()
+		if err != nil {
+			return fmt.Errorf("unable to get the rows affected: %w", err)
+		}
+		affected[rows] = struct{}{}
+		return nil
+	}
+
+	after := func() {
+		if tx != nil {
+			if err := tx.Rollback(); err != nil {
+				fatalf("unable to roll back %s: %v", v.name, err)
+			}
+		}
+		if err := harness.Exec(ctx, s.conn, v.cleanup...); err != nil {
+			fatalf("unable to execute %v", err)
+		}
+		s.prepare(ctx)
+		begin()
+	}
+
+	begin()
+	duration, err := measure(ctx, exec, execs, after)
+	if tx != nil {
+		_ = tx.Rollback()
+	}
+	if err != nil {
+		fatalf("unable to execute %s: %v", v.name, err)
+	}
+
+	return cell{duration: duration.Round(time.Millisecond), note: rowsAffectedNote(v.name, affected)}
+}
+
+// rowsAffectedNote reports the rows affected by the executions of a variant
+// and whether they match the label of the query.
+func rowsAffectedNote(name string, affected map[int64]struct{}) string {
+	expected, labelled := labeledRows(name)
+	if len(affected) == 1 {
+		for rows := range affected {
+			if !labelled || rows == expected {
+				return fmt.Sprintf("%d rows affected", rows)
+			}
+			return fmt.Sprintf("%d rows affected, expected %d", rows, expected)
+		}
+	}
+
+	min, max := int64(-1), int64(-1)
+	for rows := range affected {
+		if min < 0 || rows < min {
+			min = rows
+		}
+		if rows > max {
+			max = rows
+		}
+	}
+	if labelled {
+		return fmt.Sprintf("%d-%d rows affected, expected %d", min, max, expected)
+	}
+	return fmt.Sprintf("%d-%d rows affected", min, max)
+}
@@ -0,0 +1,39 @@
+package main
+
+import "testing"
+
+func TestRowsAffectedNote(t *testing.T) {
+	data := []struct {
+		name     string
+		affected []int64
+		expected string
+	}{
+		{"a - insert 1,000 rows", []int64{1000}, "1000 rows affected"},
+		{"a - insert 1,000 rows [dop 2]", []int64{999}, "999 rows affected, expected 1000"},
+		{"b - update", []int64{0, 1000}, "0-1000 rows affected"},
+		{"b - update 1,000 rows", []int64{1000, 0}, "0-1000 rows affected, expected 1000"},
+	}
+
+	for _, d := range data {
+		affected := make(map[int64]struct{})
+		for _, rows := range d.affected {
+			affected[rows] = struct{}{}
+		}
+		if actual := rowsAffectedNote(d.name, affected); actual != d.expected {
+			t.Errorf("rowsAffectedNote(%q, %v) = %q, expected %q", d.name, d.affected, actual, d.expected)
+		}
+	}
+}
+
+func TestResetStatements(t *testing.T) {
+	test := Tests["write-commit"]
+	name := "c - delete 1,000 rows"
+
+	expected := "insert into transactions_dml_sf10 (id, description, ts) select id, description, ts from transactions_sf10 where id <= 1000;"
+	if actual := resetStatements(test, target{connectionName: PostgreSql17, engine: enginePostgres}, name, 10); len(actual) != 1 || actual[0] != expected {
+		t.Errorf("resetStatements() = %q, expected %q", actual, expected)
+	}
+	if actual := resetStatements(Tests["write-rollback"], target{connectionName: PostgreSql17, engine: enginePostgres}, name, 1); actual != nil {
+		t.Errorf("resetStatements() of a rolled back test = %q", actual)
+	}
+}
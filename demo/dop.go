@@ -0,0 +1,102 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// parseDopLevels parses the -dop flag. DOP 1 is always included because
+// speedups are reported against it.
+func parseDopLevels(s string) ([]int, error) {
+	if s == "" {
+		return nil, nil
+	}
+
+	seen := map[int]struct{}{1: {}}
+	dops := []int{1}
+	for _, part := range strings.Split(s, ",") {
+		dop, err := strconv.Atoi(strings.TrimSpace(part))
+		if err != nil || dop < 1 {
+			return nil, fmt.Errorf("invalid degree of parallelism %q", part)
+		}
+		if _, ok := seen[dop]; ok {
+			continue
+		}
+		seen[dop] = struct{}{}
+		dops = append(dops, dop)
+	}
+	sort.Ints(dops)
+
+	return dops, nil
+}
+
+var dopSuffix = regexp.MustCompile(`^(.*) \[dop (\d+)\]$`)
+
+func dopVariantName(name string, dop int) string {
+	return fmt.Sprintf("%s [dop %d]", name, dop)
+}
+
+// dopVariants returns a variant of v for every degree of parallelism the
+// engine can be limited to. Engines without a parallelism knob only get v.
+func dopVariants(engine string, v variant, dops []int) []variant {
+	variants := make([]variant, 0, len(dops))
+	for _, dop := range dops {
+		dv, ok := dopVariant(engine, v, dop)
+		if !ok {
+			return []variant{v}
+		}
+		variants = append(variants, dv)
+	}
+	return variants
+}
+
+func dopVariant(engine string, v variant, dop int) (variant, bool) {
+	dv := v
+	dv.name = dopVariantName(v.name, dop)
+
+	switch engine {
+	case enginePostgres:
+		// the leader process takes part in the execution, so DOP n means n-1 workers
+		dv.setup = append(append([]string{}, v.setup...), fmt.Sprintf("set max_parallel_workers_per_gather = %d", dop-1))
+		dv.teardown = append(append([]string{}, v.teardown...), "reset max_parallel_workers_per_gather")
+	case engineMsSql:
+		dv.query = withQueryOption(v.query, fmt.Sprintf("maxdop %d", dop))
+	case engineMySql:
+		// only affects clustered index scans (count(*), check table), the rest of the plan is single threaded
+		dv.setup = append(append([]string{}, v.setup...), fmt.Sprintf("set session innodb_parallel_read_threads = %d", dop))
+		dv.teardown = append(append([]string{}, v.teardown...), "set session innodb_parallel_read_threads = default")
+	default:
+		return v, false
+	}
+
+	return dv, true
+}
+
+var queryOption = regexp.MustCompile(`(?is)\boption\s*\((.*)\)\s*;?\s*$`)
+
+// withQueryOption adds a query hint to the MSSQL option clause, creating the
+// clause when the query does not have one yet.
+func withQueryOption(query string, option string) string {
+	if m := queryOption.FindStringSubmatchIndex(query); m != nil {
+		return query[:m[2]] + option + ", " + query[m[2]:]
+	}
+	return strings.TrimRight(strings.TrimSpace(query), ";") + " option (" + option + ");"
+}
+
+// addSpeedups notes the speedup of every DOP variant against DOP 1 of the same query.
+func addSpeedups(cells map[string]cell) {
+	for name, c := range cells {
+		m := dopSuffix.FindStringSubmatch(name)
+		if m == nil || m[2] == "1" {
+			continue
+		}
+		base, ok := cells[dopVariantName(m[1], 1)]
+		if !ok || c.duration == 0 {
+			continue
+		}
+		cells[name] = c.withNote(fmt.Sprintf("x%.2f", float64(base.duration)/float64(c.duration)))
+	}
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestWithQueryOption(t *testing.T) {
+	data := []struct {
+		query    string
+		expected string
+	}{
+		{"select 1;", "select 1 option (maxdop 2);"},
+		{"select 1", "select 1 option (maxdop 2);"},
+		{"select 1 option (hash join);", "select 1 option (maxdop 2, hash join);"},
+	}
+
+	for _, d := range data {
+		if actual := withQueryOption(d.query, "maxdop 2"); actual != d.expected {
+			t.Errorf("withQueryOption(%q) = %q, expected %q", d.query, actual, d.expected)
+		}
+	}
+}
+
+func TestParseDopLevels(t *testing.T) {
+	dops, err := parseDopLevels("4, 2,4")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(dops) != 3 || dops[0] != 1 || dops[1] != 2 || dops[2] != 4 {
+		t.Errorf("unexpected levels: %v", dops)
+	}
+
+	if _, err := parseDopLevels("0"); err == nil {
+		t.Error("expected an error for DOP 0")
+	}
+}
+
+func TestAddSpeedups(t *testing.T) {
+	cells := map[string]cell{
+		dopVariantName("a", 1): {duration: 4 * time.Second},
+		dopVariantName("a", 4): {duration: 1 * time.Second},
+	}
+	addSpeedups(cells)
+
+	if note := cells[dopVariantName("a", 4)].note; note != "x4.00" {
+		t.Errorf("unexpected speedup note %q", note)
+	}
+	if note := cells[dopVariantName("a", 1)].note; note != "" {
+		t.Errorf("unexpected note for DOP 1: %q", note)
+	}
+}
@@ -0,0 +1,69 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"log"
+
+	"github.com/docker/docker/client"
+)
+
+var exclusiveFlag = flag.Bool("exclusive", false, "pause the containers of the other targets while a target is measured")
+
+// exclusiveContainers returns the containers to pause while a target is
+// measured: the ones of the other targets, once each. Targets of the profiles
+// of a server share its container.
+func exclusiveContainers(t target, targets []target) []string {
+	seen := map[string]struct{}{t.container: {}, "": {}}
+	var containers []string
+	for _, other := range targets {
+		if _, ok := seen[other.container]; ok {
+			continue
+		}
+		seen[other.container] = struct{}{}
+		containers = append(containers, other.container)
+	}
+	return containers
+}
+
+// pauseOthers pauses the running containers of the other targets, so
+// checkpoints, autovacuum or startup tasks of another engine do not take CPU
+// from the target being measured. The returned function resumes them.
+func pauseOthers(ctx context.Context, cli *client.Client, t target, targets []target) (func(), error) {
+	var paused []string
+	resume := func() {
+		for _, id := range paused {
+			// the measurement may have been interrupted, the containers still have to run again
+			if err := cli.ContainerUnpause(context.Background(), id); err != nil {
+				log.Printf("unable to resume container %s: %v", id, err)
+			}
+		}
+	}
+
+	for _, id := range exclusiveContainers(t, targets) {
+		state, err := cli.ContainerInspect(ctx, id)
+		if err != nil {
+			return resume, fmt.Errorf("unable to inspect container %s: %w", id, err)
+		}
+		if !state.State.Running || state.State.Paused {
+			continue
+		}
+		if err := cli.ContainerPause(ctx, id); err != nil {
+			return resume, fmt.Errorf("unable to pause container %s: %w", id, err)
+		}
+		paused = append(paused, id)
+	}
+	return resume, nil
+}
+
+// exclusiveClient connects to the docker daemon for the exclusive mode, every
+// target needs to know its container.
+func exclusiveClient(targets []target) (*client.Client, error) {
+	for _, t := range targets {
+		if t.container == "" {
+			return nil, fmt.Errorf("%s has no container to pause, set it in the config or use -containers", t.connectionName)
+		}
+	}
+	return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+}
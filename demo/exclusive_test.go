@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExclusiveContainers(t *testing.T) {
+	targets := []target{
+		{connectionName: "pg-17.5 [default]", container: "rdbms-postgres17"},
+		{connectionName: "pg-17.5 [no-jit]", container: "rdbms-postgres17"},
+		{connectionName: MySql9, container: "rdbms-mysql9"},
+		{connectionName: MySql8, container: "rdbms-mysql8"},
+	}
+
+	data := []struct {
+		target   target
+		expected string
+	}{
+		{targets[0], "rdbms-mysql9,rdbms-mysql8"},
+		{targets[1], "rdbms-mysql9,rdbms-mysql8"},
+		{targets[2], "rdbms-postgres17,rdbms-mysql8"},
+		{target{connectionName: PostgreSql16}, "rdbms-postgres17,rdbms-mysql9,rdbms-mysql8"},
+	}
+
+	for _, d := range data {
+		if actual := strings.Join(exclusiveContainers(d.target, targets), ","); actual != d.expected {
+			t.Errorf("exclusiveContainers(%s) = %q, expected %q", d.target.connectionName, actual, d.expected)
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package main
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/solontsev/rdbms-performance-comparison/schema"
+)
+
+// fixture is a table the demo tests query, with content that only depends on
+// the seed, so every engine gets identical data.
+type fixture struct {
+	table schema.Table
+	rows  int
+	// fixed tables keep their size at every scale factor
+	fixed bool
+	// base is the name of the unscaled table, the generator is seeded with it
+	base string
+	// generate emits the given number of rows in primary key order
+	generate func(rows int, rnd *rand.Rand, emit func(values ...any))
+	// after runs once the data and the indexes are loaded, by engine
+	after map[string][]string
+}
+
+// rand returns the generator of the fixture rows.
+func (f fixture) rand() *rand.Rand {
+	if f.base != "" {
+		return fixtureRand(f.base)
+	}
+	return fixtureRand(f.table.Name)
+}
+
+var baseTs = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// sequence generates rows with ids starting at first.
+func sequence(first int, row func(id int, rnd *rand.Rand) []any) func(int, *rand.Rand, func(...any)) {
+	return func(n int, rnd *rand.Rand, emit func(...any)) {
+		for id := first; id < first+n; id++ {
+			emit(row(id, rnd)...)
+		}
+	}
+}
+
+// country gives the "1 row / 9 rows / 90 rows / 900 rows / 4000 rows" distribution
+// the index-seek-vs-scan tests rely on.
+func country(id int) string {
+	switch {
+	case id%10000 == 0:
+		return "UK"
+	case id%1000 == 0:
+		return "NL"
+	case id%100 == 0:
+		return "FR"
+	case id%10 == 0:
+		return "CY"
+	case id%2 == 0:
+		return "US"
+	case id%3 == 0:
+		return "DE"
+	default:
+		return "XX"
+	}
+}
+
+func status(id int) (int, string) {
+	if id%10 == 0 {
+		return 0, "deleted"
+	}
+	return 1, "active"
+}
+
+var clientColumns = []schema.Column{
+	{Name: "id", Type: schema.Int},
+	{Name: "name", Type: schema.Varchar, Size: 100},
+	{Name: "country", Type: schema.Char, Size: 2},
+	{Name: "insert_dt", Type: schema.Timestamp},
+}
+
+func clientRow(id int, _ *rand.Rand) []any {
+	return []any{id, fmt.Sprintf("client_%d", id), country(id), baseTs.Add(time.Duration(id) * time.Second)}
+}
+
+var filterColumns = []schema.Column{
+	{Name: "id", Type: schema.Int},
+	{Name: "data", Type: schema.Char, Size: 100},
+	{Name: "status_id_tinyint", Type: schema.TinyInt},
+	{Name: "status_id_int", Type: schema.Int},
+	{Name: "status_char", Type: schema.Char, Size: 7},
+	{Name: "status_varchar", Type: schema.Varchar, Size: 7},
+	{Name: "status_text", Type: schema.Text},
+}
+
+func filterRow(id int, _ *rand.Rand) []any {
+	statusId, statusName := status(id)
+	return []any{id, strings.Repeat("a", 100), statusId, statusId, statusName, statusName, statusName}
+}
+
+var transactionColumns = []schema.Column{
+	{Name: "id", Type: schema.Int},
+	{Name: "description", Type: schema.Varchar, Size: 100},
+	{Name: "ts", Type: schema.Timestamp},
+}
+
+func transactionRow(id int, _ *rand.Rand) []any {
+	return []any{id, fmt.Sprintf("desc_%d_%s", id, strings.Repeat("x", 50)), baseTs.Add(time.Duration(id) * time.Second)}
+}
+
+// orderDetails generates 10 lines with distinct products for every order.
+func orderDetails(rows int, rnd *rand.Rand, emit func(...any)) {
+	for orderId := 1; orderId <= rows/10; orderId++ {
+		products := make(map[int]struct{}, 10)
+		for len(products) < 10 {
+			productId := rnd.Intn(1000000)
+			if _, ok := products[productId]; ok {
+				continue
+			}
+			products[productId] = struct{}{}
+			emit(orderId, productId, rnd.Intn(10)+1, float64(rnd.Intn(100)))
+		}
+	}
+}
+
+// updateAll rewrites every row of the table, leaving dead row versions behind
+// on engines with MVCC in the table.
+func updateAll(table string) []string {
+	return []string{fmt.Sprintf("update %s set description = description", table)}
+}
+
+var fixtures = []fixture{
+	{
+		table: schema.Table{
+			Name:       "numbers",
+			Columns:    []schema.Column{{Name: "id", Type: schema.Int}},
+			PrimaryKey: []string{"id"},
+		},
+		rows:     10000,
+		fixed:    true,
+		generate: sequence(0, func(id int, _ *rand.Rand) []any { return []any{id} }),
+	},
+	{
+		table: schema.Table{
+			Name:       "client",
+			Columns:    clientColumns,
+			PrimaryKey: []string{"id"},
+			Indexes:    []schema.Index{{Name: "idx_client_country", Columns: []string{"country"}}},
+		},
+		rows:     10000,
+		generate: sequence(1, clientRow),
+	},
+	{
+		table: schema.Table{
+			Name:       "client_large",
+			Columns:    clientColumns,
+			PrimaryKey: []string{"id"},
+			Indexes:    []schema.Index{{Name: "idx_client_large_country", Columns: []string{"country"}}},
+		},
+		rows:     1000000,
+		generate: sequence(1, clientRow),
+	},
+	{
+		table: schema.Table{
+			Name:       "client_ex",
+			Columns:    []schema.Column{{Name: "id", Type: schema.Int}, {Name: "address", Type: schema.Varchar, Size: 1000, Nullable: true}},
+			PrimaryKey: []string{"id"},
+		},
+		rows: 10000,
+		generate: sequence(1, func(id int, _ *rand.Rand) []any {
+			return []any{id, fmt.Sprintf("client_%d_%s", id, strings.Repeat("x", 900))}
+		}),
+	},
+	{
+		table: schema.Table{
+			Name: "order",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.Int},
+				{Name: "dt", Type: schema.Timestamp},
+				{Name: "client_id", Type: schema.Int},
+				{Name: "pay_type", Type: schema.Int},
+				{Name: "group_id", Type: schema.Int},
+			},
+			PrimaryKey: []string{"id"},
+			Indexes: []schema.Index{
+				{Name: "idx_product_client_id", Columns: []string{"client_id"}},
+				{Name: "idx_order_pay_type", Columns: []string{"pay_type"}},
+				{Name: "idx_order_group_id", Columns: []string{"group_id"}},
+			},
+		},
+		rows: 100000,
+		generate: sequence(1, func(id int, rnd *rand.Rand) []any {
+			return []any{id, baseTs.Add(time.Duration(id) * time.Second), rnd.Intn(10000), rnd.Intn(5), rnd.Intn(1000)}
+		}),
+	},
+	{
+		table: schema.Table{
+			Name: "product",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.Int},
+				{Name: "name", Type: schema.Varchar, Size: 100},
+				{Name: "insert_dt", Type: schema.Timestamp},
+			},
+			PrimaryKey: []string{"id"},
+		},
+		rows: 1000000,
+		generate: sequence(1, func(id int, _ *rand.Rand) []any {
+			return []any{id, fmt.Sprintf("product_%d", id), baseTs.Add(time.Duration(id) * time.Second)}
+		}),
+	},
+	{
+		table: schema.Table{
+			Name: "order_detail",
+			Columns: []schema.Column{
+				{Name: "order_id", Type: schema.Int},
+				{Name: "product_id", Type: schema.Int},
+				{Name: "quantity", Type: schema.Int},
+				{Name: "price", Type: schema.Decimal, Size: 10, Scale: 2},
+			},
+			PrimaryKey: []string{"order_id", "product_id"},
+			Indexes:    []schema.Index{{Name: "idx_order_detail_product", Columns: []string{"product_id"}}},
+		},
+		rows:     1000000,
+		generate: orderDetails,
+	},
+	{
+		table: schema.Table{
+			Name:    "filter_10m",
+			Columns: filterColumns,
+		},
+		rows:     10000000,
+		generate: sequence(1, filterRow),
+	},
+	{
+		table: schema.Table{
+			Name:    "filter_1m",
+			Columns: filterColumns,
+		},
+		rows:     1000000,
+		generate: sequence(1, filterRow),
+	},
+	{
+		table: schema.Table{
+			Name:       "filter_1m_with_pk",
+			Columns:    filterColumns,
+			PrimaryKey: []string{"id"},
+		},
+		rows:     1000000,
+		generate: sequence(1, filterRow),
+	},
+	{
+		table: schema.Table{
+			Name: "large_group_by_table",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.Int},
+				{Name: "c1", Type: schema.Int},
+				{Name: "c2", Type: schema.Int},
+				{Name: "c3", Type: schema.Int},
+				{Name: "c4", Type: schema.Int},
+				{Name: "data", Type: schema.Char, Size: 200},
+			},
+			PrimaryKey: []string{"id"},
+			Indexes: []schema.Index{
+				{Name: "idx_large_group_by_table_c1_c2_c3_c4", Columns: []string{"c1", "c2", "c3", "c4"}},
+				{Name: "idx_large_group_by_table_c2", Columns: []string{"c2"}},
+				{Name: "idx_large_group_by_table_c3", Columns: []string{"c3"}},
+			},
+		},
+		rows: 1000000,
+		generate: sequence(1, func(id int, rnd *rand.Rand) []any {
+			return []any{id, rnd.Intn(10), rnd.Intn(100), rnd.Intn(1000), rnd.Intn(1000000), strings.Repeat("x", 200)}
+		}),
+	},
+	{
+		table: schema.Table{
+			Name: "group_by_table",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.Int},
+				{Name: "a", Type: schema.Int},
+				{Name: "b", Type: schema.Int},
+				{Name: "c", Type: schema.Int},
+			},
+			PrimaryKey: []string{"id"},
+			Indexes: []schema.Index{
+				{Name: "idx_group_by_table_a", Columns: []string{"a"}},
+				{Name: "idx_group_by_table_b", Columns: []string{"b"}},
+				{Name: "idx_group_by_table_c", Columns: []string{"c"}},
+			},
+		},
+		rows: 1000000,
+		generate: sequence(1, func(id int, rnd *rand.Rand) []any {
+			return []any{id, rnd.Intn(100000), rnd.Intn(1000), rnd.Intn(10)}
+		}),
+	},
+	{
+		table: schema.Table{
+			Name: "skip_scan_example",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.Int},
+				{Name: "a", Type: schema.Int},
+				{Name: "b", Type: schema.Int},
+				{Name: "c", Type: schema.Int},
+			},
+			PrimaryKey: []string{"id"},
+			Indexes:    []schema.Index{{Name: "idx_skip_scan_example_a_b", Columns: []string{"a", "b"}}},
+		},
+		rows: 1000000,
+		generate: sequence(1, func(id int, rnd *rand.Rand) []any {
+			return []any{id, rnd.Intn(10), rnd.Intn(1000), rnd.Intn(100000)}
+		}),
+	},
+	{
+		table: schema.Table{
+			Name:       "transactions",
+			Columns:    transactionColumns,
+			PrimaryKey: []string{"id"},
+			Indexes:    []schema.Index{{Name: "ix_ts_description", Columns: []string{"ts"}, Include: []string{"description"}}},
+		},
+		rows:     1000000,
+		generate: sequence(1, transactionRow),
+	},
+	{
+		table: schema.Table{
+			Name:       "transactions_modified",
+			Columns:    transactionColumns,
+			PrimaryKey: []string{"id"},
+			Indexes:    []schema.Index{{Name: "ix_transactions_modified__ts_description", Columns: []string{"ts"}, Include: []string{"description"}}},
+		},
+		rows:     1000000,
+		generate: sequence(1, transactionRow),
+		after: map[string][]string{
+			// keep the visibility map stale, so index only scans have to visit the heap
+			enginePostgres: append([]string{"alter table transactions_modified set (autovacuum_enabled = false)"}, updateAll("transactions_modified")...),
+			engineMySql:    updateAll("transactions_modified"),
+			engineMariaDb:  updateAll("transactions_modified"),
+			engineMsSql:    updateAll("transactions_modified"),
+		},
+	},
+	{
+		table: schema.Table{
+			Name:       "transactions_wo_covered_index",
+			Columns:    transactionColumns,
+			PrimaryKey: []string{"id"},
+			Indexes:    []schema.Index{{Name: "ix_transactions_wo_covered_index__ts", Columns: []string{"ts"}}},
+		},
+		rows:     1000000,
+		generate: sequence(1, transactionRow),
+		after: map[string][]string{
+			enginePostgres: append([]string{"alter table transactions_wo_covered_index set (autovacuum_enabled = false)"}, updateAll("transactions_wo_covered_index")...),
+			engineMySql:    updateAll("transactions_wo_covered_index"),
+			engineMariaDb:  updateAll("transactions_wo_covered_index"),
+			engineMsSql:    updateAll("transactions_wo_covered_index"),
+		},
+	},
+	{
+		// written by the DML tests, the same rows as transactions
+		table: schema.Table{
+			Name:       "transactions_dml",
+			Columns:    transactionColumns,
+			PrimaryKey: []string{"id"},
+			Indexes:    []schema.Index{{Name: "ix_transactions_dml__ts", Columns: []string{"ts"}}},
+		},
+		rows:     1000000,
+		base:     "transactions",
+		generate: sequence(1, transactionRow),
+	},
+	{
+		// columns with the distributions that break statistics: skew,
+		// correlation, NULLs and hot ranges
+		table: schema.Table{
+			Name: "skewed",
+			Columns: []schema.Column{
+				{Name: "id", Type: schema.Int},
+				{Name: "uniform_id", Type: schema.Int},
+				{Name: "zipf_id", Type: schema.Int},
+				{Name: "correlated_id", Type: schema.Int},
+				{Name: "nullable_id", Type: schema.Int, Nullable: true},
+				{Name: "hot_id", Type: schema.Int},
+				{Name: "status", Type: schema.Varchar, Size: 10},
+			},
+			PrimaryKey: []string{"id"},
+			Indexes: []schema.Index{
+				{Name: "idx_skewed_uniform_id", Columns: []string{"uniform_id"}},
+				{Name: "idx_skewed_zipf_id", Columns: []string{"zipf_id"}},
+				{Name: "idx_skewed_correlated_id", Columns: []string{"correlated_id"}},
+				{Name: "idx_skewed_nullable_id", Columns: []string{"nullable_id"}},
+				{Name: "idx_skewed_hot_id", Columns: []string{"hot_id"}},
+				{Name: "idx_skewed_status", Columns: []string{"status"}},
+			},
+		},
+		rows: 1000000,
+		generate: profile(
+			uniform(1, 1000),
+			zipf(1.2, 1000),
+			correlated(2, 0.9, uniform(1, 1000)),
+			withNulls(0.3, uniform(1, 1000)),
+			hotRange(1, 10, 0.8, uniform(1, 100000)),
+			labels([]string{"active", "pending", "blocked", "deleted", "archived"}, zipf(2, 5)),
+		),
+	},
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// access path hints a test can ask to be forced
+const hintForceSeek = "force seek"
+const hintForceScan = "force scan"
+const hintForceIndex = "force index"
+const hintIgnoreIndex = "ignore index"
+
+// hint is an access path forced on one table of the queries of a test.
+type hint struct {
+	kind  string
+	table string
+	// index is required for hintForceIndex and hintIgnoreIndex and optional for hintForceSeek
+	index string
+}
+
+func (h hint) String() string {
+	if h.index == "" || h.kind == hintForceScan {
+		return h.kind
+	}
+	return fmt.Sprintf("%s %s", h.kind, h.index)
+}
+
+func hintVariantName(name string, h hint) string {
+	return fmt.Sprintf("%s [%s]", name, h)
+}
+
+// hintVariant translates the hint into the engine syntax: table hints for
+// MSSQL, index hints for MySQL and MariaDB, planner settings or pg_hint_plan
+// comments for PostgreSQL. When the engine has no equivalent, the returned
+// variant is skipped.
+func hintVariant(t target, v variant, h hint) variant {
+	hv := v
+	hv.name = hintVariantName(v.name, h)
+	hv.setup = append([]string{}, v.setup...)
+	hv.teardown = append([]string{}, v.teardown...)
+
+	if (h.kind == hintForceIndex || h.kind == hintIgnoreIndex) && h.index == "" {
+		hv.skip = fmt.Sprintf("%s needs an index name", h.kind)
+		return hv
+	}
+
+	refs := tableRefs(v.query)
+	var aliases []string
+	for _, ref := range refs {
+		if strings.EqualFold(ref.name, h.table) {
+			aliases = append(aliases, ref.alias)
+		}
+	}
+	if len(aliases) == 0 {
+		hv.skip = fmt.Sprintf("table %s is not used", h.table)
+		return hv
+	}
+
+	switch t.engine {
+	case engineMsSql:
+		tableHints := map[string]string{hintForceSeek: "forceseek", hintForceScan: "forcescan", hintForceIndex: fmt.Sprintf("index(%s)", h.index)}
+		if h.kind == hintForceSeek && h.index != "" {
+			tableHints[hintForceSeek] = fmt.Sprintf("forceseek, index(%s)", h.index)
+		}
+		tableHint, ok := tableHints[h.kind]
+		if !ok {
+			hv.skip = fmt.Sprintf("%s is not supported", h.kind)
+			break
+		}
+		hv.query = withTableHint(v.query, h.table, " with ("+tableHint+")")
+	case engineMySql, engineMariaDb:
+		switch {
+		case h.kind == hintForceScan:
+			// an empty index list disables all indexes of the table
+			hv.query = withTableHint(v.query, h.table, " use index ()")
+		case h.index != "":
+			hints := map[string]string{hintForceSeek: "force", hintForceIndex: "force", hintIgnoreIndex: "ignore"}
+			hv.query = withTableHint(v.query, h.table, fmt.Sprintf(" %s index (%s)", hints[h.kind], h.index))
+		case t.engine == engineMySql && h.kind == hintForceSeek:
+			hv.query = withOptimizerHint(v.query, fmt.Sprintf("INDEX(%s)", strings.Join(aliases, " ")))
+		default:
+			hv.skip = fmt.Sprintf("%s without an index name is not supported", h.kind)
+		}
+	case enginePostgres:
+		switch {
+		case h.kind == hintForceIndex || (h.kind == hintForceSeek && h.index != ""):
+			if !t.pgHintPlan {
+				hv.skip = fmt.Sprintf("%s needs pg_hint_plan", h.kind)
+				break
+			}
+			hints := make([]string, 0, len(aliases))
+			for _, alias := range aliases {
+				hints = append(hints, fmt.Sprintf("IndexScan(%s %s)", alias, h.index))
+			}
+			hv.setup = append(hv.setup, "load 'pg_hint_plan'")
+			hv.query = "/*+ " + strings.Join(hints, " ") + " */ " + v.query
+		case h.kind == hintForceSeek:
+			// planner settings apply to every table of the query, not only to the hinted one
+			hv.setup = append(hv.setup, "set enable_seqscan = off")
+			hv.teardown = append(hv.teardown, "reset enable_seqscan")
+		case h.kind == hintForceScan:
+			for _, setting := range []string{"enable_indexscan", "enable_indexonlyscan", "enable_bitmapscan"} {
+				hv.setup = append(hv.setup, fmt.Sprintf("set %s = off", setting))
+				hv.teardown = append(hv.teardown, fmt.Sprintf("reset %s", setting))
+			}
+		default:
+			hv.skip = fmt.Sprintf("%s is not supported", h.kind)
+		}
+	default:
+		hv.skip = fmt.Sprintf("%s is not supported", h.kind)
+	}
+
+	return hv
+}
+
+// withTableHint appends the hint text after every reference to the table.
+func withTableHint(query string, table string, tableHint string) string {
+	refs := tableRefs(query)
+	sort.Slice(refs, func(i, j int) bool { return refs[i].end > refs[j].end })
+	for _, ref := range refs {
+		if strings.EqualFold(ref.name, table) {
+			query = query[:ref.end] + tableHint + query[ref.end:]
+		}
+	}
+	return query
+}
+
+// hasHintPlan reports whether the pg_hint_plan extension is installed on the server.
+func hasHintPlan(ctx context.Context, db *sql.DB) bool {
+	var count int
+	err := db.QueryRowContext(ctx, "select count(*) from pg_available_extensions where name = 'pg_hint_plan'").Scan(&count)
+	if err != nil {
+		fatalf("unable to check pg_hint_plan: %v", err)
+	}
+	return count > 0
+}
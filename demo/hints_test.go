@@ -0,0 +1,38 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestHintVariant(t *testing.T) {
+	query := "select min(name) from client where country = 'FR';"
+	data := []struct {
+		engine   string
+		h        hint
+		expected string
+		skip     bool
+	}{
+		{engineMsSql, hint{kind: hintForceSeek, table: "client"}, "select min(name) from client with (forceseek) where country = 'FR';", false},
+		{engineMsSql, hint{kind: hintIgnoreIndex, table: "client", index: "idx_client_country"}, query, true},
+		{engineMySql, hint{kind: hintForceIndex, table: "client", index: "idx_client_country"}, "select min(name) from client force index (idx_client_country) where country = 'FR';", false},
+		{engineMySql, hint{kind: hintForceSeek, table: "client"}, "select /*+ INDEX(client) */ min(name) from client where country = 'FR';", false},
+		{engineMySql, hint{kind: hintIgnoreIndex, table: "client", index: "idx_client_country"}, "select min(name) from client ignore index (idx_client_country) where country = 'FR';", false},
+		// an ignore or force index hint without an index name must not become the INDEX() hint
+		{engineMySql, hint{kind: hintIgnoreIndex, table: "client"}, query, true},
+		{engineMySql, hint{kind: hintForceIndex, table: "client"}, query, true},
+		{engineMariaDb, hint{kind: hintForceScan, table: "client"}, "select min(name) from client use index () where country = 'FR';", false},
+		{enginePostgres, hint{kind: hintForceSeek, table: "client"}, query, false},
+		{enginePostgres, hint{kind: hintForceIndex, table: "client", index: "idx_client_country"}, query, true},
+		{enginePostgres, hint{kind: hintForceSeek, table: "order"}, query, true},
+	}
+
+	for _, d := range data {
+		v := hintVariant(target{engine: d.engine}, variant{name: "a", query: query}, d.h)
+		if (v.skip != "") != d.skip {
+			t.Errorf("%s %s: unexpected skip %q", d.engine, d.h, v.skip)
+		}
+		if !d.skip && v.query != d.expected {
+			t.Errorf("%s %s: query = %q, expected %q", d.engine, d.h, v.query, d.expected)
+		}
+	}
+}
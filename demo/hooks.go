@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/solontsev/rdbms-performance-comparison/harness"
+)
+
+// hooks are statements by engine that prepare the data or the session for a
+// test or a variant and clean up after it, e.g. an update that leaves dead
+// rows behind or a statistics rebuild. They are not measured.
+type hooks struct {
+	setup    map[string][]string
+	teardown map[string][]string
+}
+
+// runSetup runs the setup statements of the engine and returns a note for the
+// result cells when one of them fails.
+func (h hooks) runSetup(ctx context.Context, conn harness.Execer, engine string, sf float64) string {
+	if err := harness.Exec(ctx, conn, scaleStatements(h.setup[engine], sf)...); err != nil {
+		return fmt.Sprintf("setup failed: %v", err)
+	}
+	return ""
+}
+
+// runTeardown runs the teardown statements of the engine and returns a note
+// for the result cells when one of them fails.
+func (h hooks) runTeardown(ctx context.Context, conn harness.Execer, engine string, sf float64) string {
+	if err := harness.Exec(ctx, conn, scaleStatements(h.teardown[engine], sf)...); err != nil {
+		return fmt.Sprintf("teardown failed: %v", err)
+	}
+	return ""
+}
+
+// hooksOf returns the variant hooks of a variant: the ones of its name, else
+// the ones of the query it is generated from.
+func (test testData) hooksOf(v variant) hooks {
+	if h, ok := test.variantHooks[v.name]; ok {
+		return h
+	}
+	return test.variantHooks[v.queryName]
+}
+
+func scaleStatements(statements []string, sf float64) []string {
+	scaled := make([]string, len(statements))
+	for i, s := range statements {
+		scaled[i] = scaleQuery(s, sf)
+	}
+	return scaled
+}
@@ -0,0 +1,30 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestHooksOf(t *testing.T) {
+	query := hooks{setup: map[string][]string{engineMySql: {"analyze table client"}}}
+	forced := hooks{setup: map[string][]string{engineMySql: {"set session optimizer_switch = 'hash_join=off'"}}}
+	test := testData{variantHooks: map[string]hooks{
+		"a":                query,
+		"a [nested loops]": forced,
+	}}
+
+	data := []struct {
+		variant  variant
+		expected hooks
+	}{
+		{variant{name: "a", queryName: "a"}, query},
+		{variant{name: "a [nested loops]", queryName: "a"}, forced},
+		{variant{name: "a [dop 2]", queryName: "a"}, query},
+		{variant{name: "a-recursive", queryName: "a-recursive"}, hooks{}},
+	}
+	for _, d := range data {
+		if actual := test.hooksOf(d.variant); !reflect.DeepEqual(actual, d.expected) {
+			t.Errorf("hooksOf(%s) = %+v, expected %+v", d.variant.name, actual, d.expected)
+		}
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+// join algorithms a test can ask to be forced
+const joinNestedLoop = "nested loop"
+const joinHash = "hash"
+const joinMerge = "merge"
+
+func joinVariantName(name string, algorithm string) string {
+	return fmt.Sprintf("%s [%s join]", name, algorithm)
+}
+
+// joinVariant forces every join of the query to use the algorithm. When the
+// engine has no way to force it, the returned variant is skipped.
+func joinVariant(engine string, v variant, algorithm string) variant {
+	jv := v
+	jv.name = joinVariantName(v.name, algorithm)
+	jv.setup = append([]string{}, v.setup...)
+	jv.teardown = append([]string{}, v.teardown...)
+	jv.verify = func(ctx context.Context, conn *sql.Conn, query string) (string, error) {
+		return verifyJoin(ctx, conn, engine, query, algorithm)
+	}
+
+	switch engine {
+	case engineMsSql:
+		hints := map[string]string{joinNestedLoop: "loop join", joinHash: "hash join", joinMerge: "merge join"}
+		jv.query = withQueryOption(v.query, hints[algorithm])
+	case enginePostgres:
+		// there is no way to ask for an algorithm, only to disable the other ones
+		settings := map[string]string{joinNestedLoop: "enable_nestloop", joinHash: "enable_hashjoin", joinMerge: "enable_mergejoin"}
+		for _, a := range []string{joinNestedLoop, joinHash, joinMerge} {
+			if a == algorithm {
+				continue
+			}
+			setting := settings[a]
+			jv.setup = append(jv.setup, fmt.Sprintf("set %s = off", setting))
+			jv.teardown = append(jv.teardown, fmt.Sprintf("reset %s", setting))
+		}
+	case engineMySql:
+		switch algorithm {
+		case joinNestedLoop:
+			jv.query = withOptimizerHint(v.query, "NO_BNL()")
+		case joinHash:
+			// hash join is only considered when there is no index to look the inner rows up
+			hints := []string{"BNL()"}
+			for _, ref := range tableRefs(v.query) {
+				hints = append(hints, fmt.Sprintf("NO_JOIN_INDEX(%s)", ref.alias))
+			}
+			jv.query = withOptimizerHint(v.query, strings.Join(hints, " "))
+		default:
+			jv.skip = fmt.Sprintf("%s join is not supported", algorithm)
+		}
+	case engineMariaDb:
+		levels := map[string]string{joinNestedLoop: "0", joinHash: "4"}
+		level, ok := levels[algorithm]
+		if !ok {
+			jv.skip = fmt.Sprintf("%s join is not supported", algorithm)
+			break
+		}
+		jv.setup = append(jv.setup, "set session join_cache_level = "+level)
+		jv.teardown = append(jv.teardown, "set session join_cache_level = default")
+	default:
+		jv.skip = fmt.Sprintf("%s join is not supported", algorithm)
+	}
+
+	return jv
+}
+
+// verifyJoin checks the execution plan of the query and returns a note when
+// the engine did not use the requested join algorithm.
+func verifyJoin(ctx context.Context, conn *sql.Conn, engine string, query string, algorithm string) (string, error) {
+	var explain string
+	var markers map[string]string
+	switch engine {
+	case engineMsSql:
+		// join hints are mandatory, the query fails when the plan is not possible
+		return "", nil
+	case enginePostgres:
+		explain = "explain " + query
+		markers = map[string]string{joinNestedLoop: "nested loop", joinHash: "hash join", joinMerge: "merge join"}
+	case engineMySql:
+		explain = "explain format=tree " + query
+		markers = map[string]string{joinNestedLoop: "nested loop", joinHash: "hash join"}
+	case engineMariaDb:
+		explain = "explain " + query
+		markers = map[string]string{joinHash: "bnlh"}
+	default:
+		return "", nil
+	}
+
+	plan, err := queryPlan(ctx, conn, explain)
+	if err != nil {
+		return "", err
+	}
+	plan = strings.ToLower(plan)
+
+	var honoured bool
+	if algorithm == joinNestedLoop && engine == engineMariaDb {
+		honoured = !strings.Contains(plan, "join buffer")
+	} else {
+		honoured = strings.Contains(plan, markers[algorithm])
+	}
+	if !honoured {
+		return "not honoured", nil
+	}
+	return "", nil
+}
+
+// queryPlan returns all columns of all rows of an explain statement as a single string.
+func queryPlan(ctx context.Context, conn *sql.Conn, explain string) (string, error) {
+	rows, err := conn.QueryContext(ctx, explain)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+
+	var plan strings.Builder
+	values := make([]sql.NullString, len(columns))
+	dest := make([]any, len(columns))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		for _, v := range values {
+			plan.WriteString(v.String)
+			plan.WriteString(" ")
+		}
+		plan.WriteString("\n")
+	}
+
+	return plan.String(), rows.Err()
+}
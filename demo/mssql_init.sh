@@ -1,7 +1,7 @@
 echo "executing..."
 for i in {1..60};
 do
-    /opt/mssql-tools/bin/sqlcmd -S "tcp:localhost,1434" -U SA -P "myStrong(!)Password" -d master -i /mssql_init.sql
+    /opt/mssql-tools/bin/sqlcmd -S "tcp:localhost,1434" -U SA -P "$MSSQL_SA_PASSWORD" -d master -i /mssql_init.sql
     if [ $? -eq 0 ]
     then
         echo "mssql_init.sql completed"
@@ -0,0 +1,417 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+// property is one compared attribute of a table on a target. Targets are
+// compared on value, detail is the engine specific spelling of it.
+type property struct {
+	value  string
+	detail string
+}
+
+func (p property) String() string {
+	if p.detail == "" || p.detail == p.value {
+		return p.value
+	}
+	return fmt.Sprintf("%s (%s)", p.value, p.detail)
+}
+
+// columnInfo and indexInfo are what a target reports about a table, with the
+// types already mapped to the engine neutral names of the schema package.
+type columnInfo struct {
+	name      string
+	dataType  string
+	nullable  bool
+	collation string
+	// caseInsensitive is set when the collation ignores case
+	caseInsensitive bool
+}
+
+type indexInfo struct {
+	name    string
+	columns []string
+	include []string
+	primary bool
+}
+
+type tableInfo struct {
+	exists  bool
+	columns []columnInfo
+	indexes []indexInfo
+	// clustering is "primary key", "index <name>" or "heap"
+	clustering string
+	analyzed   bool
+	// estimatedRows is the row count the optimizer works with
+	estimatedRows int64
+}
+
+// printSchemaDiff introspects the fixture tables on every target and prints
+// them side by side, flagging every property that differs between targets.
+func printSchemaDiff(ctx context.Context) {
+	selected := selectedFixtures(*fixtureNames)
+
+	properties := make(map[string]map[string]property)
+	var names []string
+	set := func(connectionName string, name string, p property) {
+		if _, ok := properties[name]; !ok {
+			properties[name] = make(map[string]property)
+			names = append(names, name)
+		}
+		properties[name][connectionName] = p
+	}
+
+	for _, d := range databases {
+		db := openTarget(ctx, d)
+		for _, f := range selected {
+			info, err := describeTable(ctx, db, d.engine, f.table.Name)
+			if err != nil {
+				fatalf("%s: unable to describe %s: %v", d.connectionName, f.table.Name, err)
+			}
+			for _, p := range tableProperties(f.table.Name, info) {
+				set(d.connectionName, p.name, p.property)
+			}
+		}
+		db.Close()
+	}
+
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	header := table.Row{""}
+	for _, d := range databases {
+		header = append(header, strings.Replace(d.connectionName, "-", "\n", 1))
+	}
+	t.AppendHeader(append(header, "parity"))
+
+	mismatches := 0
+	for _, name := range names {
+		row := table.Row{name}
+		values := make(map[string]struct{})
+		for _, d := range databases {
+			p, ok := properties[name][d.connectionName]
+			if !ok {
+				p = property{value: "-"}
+			}
+			values[p.value] = struct{}{}
+			row = append(row, p.String())
+		}
+		parity := ""
+		if len(values) > 1 {
+			parity = "mismatch"
+			mismatches++
+		}
+		t.AppendRow(append(row, parity))
+	}
+	t.SetCaption("%d mismatches", mismatches)
+	t.Render()
+}
+
+type namedProperty struct {
+	name string
+	property
+}
+
+// tableProperties flattens a table description into the compared properties.
+func tableProperties(table string, info tableInfo) []namedProperty {
+	if !info.exists {
+		return []namedProperty{{table, property{value: "missing"}}}
+	}
+
+	properties := []namedProperty{{table, property{value: "present"}}}
+	// suffix is appended to the table name, ".column" or " property"
+	add := func(suffix string, p property) {
+		properties = append(properties, namedProperty{table + suffix, p})
+	}
+
+	for _, c := range info.columns {
+		null := "not null"
+		if c.nullable {
+			null = "null"
+		}
+		add("."+c.name, property{value: fmt.Sprintf("%s %s", c.dataType, null)})
+		if c.collation != "" {
+			sensitivity := "case sensitive"
+			if c.caseInsensitive {
+				sensitivity = "case insensitive"
+			}
+			add("."+c.name+" collation", property{value: sensitivity, detail: c.collation})
+		}
+	}
+
+	primaryKey := "none"
+	var indexes []indexInfo
+	for _, i := range info.indexes {
+		if i.primary {
+			primaryKey = strings.Join(i.columns, ", ")
+			continue
+		}
+		indexes = append(indexes, i)
+	}
+	add(" primary key", property{value: primaryKey})
+	add(" clustering", property{value: info.clustering})
+
+	sort.Slice(indexes, func(i, j int) bool { return indexes[i].name < indexes[j].name })
+	for _, i := range indexes {
+		value := fmt.Sprintf("(%s)", strings.Join(i.columns, ", "))
+		if len(i.include) > 0 {
+			value += fmt.Sprintf(" include (%s)", strings.Join(i.include, ", "))
+		}
+		add(" index "+i.name, property{value: value})
+	}
+
+	statistics := "not analyzed"
+	if info.analyzed {
+		statistics = "analyzed"
+	}
+	add(" statistics", property{value: statistics, detail: fmt.Sprintf("~%d rows", info.estimatedRows)})
+
+	return properties
+}
+
+func describeTable(ctx context.Context, db *sql.DB, engine string, table string) (tableInfo, error) {
+	exists, err := queryCount(ctx, db, tableExistsSql(engine, table))
+	if err != nil || exists == 0 {
+		return tableInfo{}, err
+	}
+
+	switch engine {
+	case engineMySql, engineMariaDb:
+		return describeMySqlTable(ctx, db, table)
+	case engineMsSql:
+		return describeMsSqlTable(ctx, db, table)
+	default:
+		return describePostgresTable(ctx, db, table)
+	}
+}
+
+func describePostgresTable(ctx context.Context, db *sql.DB, table string) (tableInfo, error) {
+	info := tableInfo{exists: true, clustering: "heap"}
+	relation := fmt.Sprintf(`to_regclass('"%s"')`, table)
+
+	err := scanRows(ctx, db, fmt.Sprintf(`select a.attname, format_type(a.atttypid, a.atttypmod), not a.attnotnull,
+			coalesce(case when co.collname = 'default' then (select datcollate from pg_database where datname = current_database()) else co.collname end, ''),
+			not coalesce(co.collisdeterministic, true)
+		from pg_attribute as a
+		left join pg_collation as co on co.oid = a.attcollation
+		where a.attrelid = %s and a.attnum > 0 and not a.attisdropped
+		order by a.attnum`, relation), func(rows *sql.Rows) error {
+		var c columnInfo
+		if err := rows.Scan(&c.name, &c.dataType, &c.nullable, &c.collation, &c.caseInsensitive); err != nil {
+			return err
+		}
+		c.dataType = canonicalType(c.dataType)
+		info.columns = append(info.columns, c)
+		return nil
+	})
+	if err != nil {
+		return info, err
+	}
+
+	err = scanRows(ctx, db, fmt.Sprintf(`select i.relname, ix.indisprimary, ix.indisclustered, ix.indnkeyatts,
+			array_to_string(array(select a.attname from unnest(ix.indkey) with ordinality as k(attnum, n)
+				join pg_attribute as a on a.attrelid = ix.indrelid and a.attnum = k.attnum order by k.n), ',')
+		from pg_index as ix
+		join pg_class as i on i.oid = ix.indexrelid
+		where ix.indrelid = %s`, relation), func(rows *sql.Rows) error {
+		var i indexInfo
+		var clustered bool
+		var keys int
+		var columns string
+		if err := rows.Scan(&i.name, &i.primary, &clustered, &keys, &columns); err != nil {
+			return err
+		}
+		all := strings.Split(columns, ",")
+		i.columns, i.include = all[:keys], all[keys:]
+		if clustered {
+			// CLUSTER orders the heap once, it is not maintained
+			info.clustering = fmt.Sprintf("heap, clustered once by %s", i.name)
+		}
+		info.indexes = append(info.indexes, i)
+		return nil
+	})
+	if err != nil {
+		return info, err
+	}
+
+	err = db.QueryRowContext(ctx, fmt.Sprintf(`select coalesce(s.last_analyze, s.last_autoanalyze) is not null, greatest(c.reltuples, 0)::bigint
+		from pg_class as c
+		left join pg_stat_user_tables as s on s.relid = c.oid
+		where c.oid = %s`, relation)).Scan(&info.analyzed, &info.estimatedRows)
+	return info, err
+}
+
+func describeMySqlTable(ctx context.Context, db *sql.DB, table string) (tableInfo, error) {
+	info := tableInfo{exists: true, clustering: "heap"}
+
+	err := scanRows(ctx, db, fmt.Sprintf(`select column_name, column_type, is_nullable = 'YES', coalesce(collation_name, '')
+		from information_schema.columns
+		where table_schema = database() and table_name = '%s'
+		order by ordinal_position`, table), func(rows *sql.Rows) error {
+		var c columnInfo
+		if err := rows.Scan(&c.name, &c.dataType, &c.nullable, &c.collation); err != nil {
+			return err
+		}
+		c.dataType = canonicalType(c.dataType)
+		c.caseInsensitive = strings.Contains(strings.ToLower(c.collation), "_ci")
+		info.columns = append(info.columns, c)
+		return nil
+	})
+	if err != nil {
+		return info, err
+	}
+
+	// InnoDB clusters a table by its primary key, or by a hidden row id
+	err = scanRows(ctx, db, fmt.Sprintf(`select index_name, column_name
+		from information_schema.statistics
+		where table_schema = database() and table_name = '%s'
+		order by index_name, seq_in_index`, table), func(rows *sql.Rows) error {
+		var name, column string
+		if err := rows.Scan(&name, &column); err != nil {
+			return err
+		}
+		if n := len(info.indexes); n == 0 || info.indexes[n-1].name != name {
+			info.indexes = append(info.indexes, indexInfo{name: name, primary: name == "PRIMARY"})
+			if name == "PRIMARY" {
+				info.clustering = "primary key"
+			}
+		}
+		i := &info.indexes[len(info.indexes)-1]
+		i.columns = append(i.columns, column)
+		return nil
+	})
+	if err != nil {
+		return info, err
+	}
+
+	var persisted int64
+	err = db.QueryRowContext(ctx, fmt.Sprintf(`select coalesce(table_rows, 0),
+			(select count(*) from mysql.innodb_table_stats where database_name = database() and table_name = '%s')
+		from information_schema.tables
+		where table_schema = database() and table_name = '%s'`, table, table)).Scan(&info.estimatedRows, &persisted)
+	info.analyzed = persisted > 0
+	return info, err
+}
+
+func describeMsSqlTable(ctx context.Context, db *sql.DB, table string) (tableInfo, error) {
+	info := tableInfo{exists: true, clustering: "heap"}
+
+	err := scanRows(ctx, db, fmt.Sprintf(`select c.name,
+			case when c.max_length = -1 then t.name + '(max)'
+				when t.name in ('char', 'varchar', 'binary', 'varbinary') then t.name + '(' + cast(c.max_length as varchar) + ')'
+				when t.name in ('nchar', 'nvarchar') then t.name + '(' + cast(c.max_length / 2 as varchar) + ')'
+				when t.name in ('decimal', 'numeric') then t.name + '(' + cast(c.precision as varchar) + ',' + cast(c.scale as varchar) + ')'
+				else t.name end,
+			c.is_nullable, coalesce(c.collation_name, '')
+		from sys.columns as c
+		join sys.types as t on t.user_type_id = c.user_type_id
+		where c.object_id = object_id('%s')
+		order by c.column_id`, table), func(rows *sql.Rows) error {
+		var c columnInfo
+		if err := rows.Scan(&c.name, &c.dataType, &c.nullable, &c.collation); err != nil {
+			return err
+		}
+		c.dataType = canonicalType(c.dataType)
+		c.caseInsensitive = strings.Contains(strings.ToLower(c.collation), "_ci")
+		info.columns = append(info.columns, c)
+		return nil
+	})
+	if err != nil {
+		return info, err
+	}
+
+	err = scanRows(ctx, db, fmt.Sprintf(`select i.name, i.is_primary_key, i.type_desc, ic.is_included_column, c.name
+		from sys.indexes as i
+		join sys.index_columns as ic on ic.object_id = i.object_id and ic.index_id = i.index_id
+		join sys.columns as c on c.object_id = ic.object_id and c.column_id = ic.column_id
+		where i.object_id = object_id('%s')
+		order by i.index_id, ic.is_included_column, ic.key_ordinal, ic.index_column_id`, table), func(rows *sql.Rows) error {
+		var name, typeDesc, column string
+		var primary, included bool
+		if err := rows.Scan(&name, &primary, &typeDesc, &included, &column); err != nil {
+			return err
+		}
+		if n := len(info.indexes); n == 0 || info.indexes[n-1].name != name {
+			info.indexes = append(info.indexes, indexInfo{name: name, primary: primary})
+			if typeDesc == "CLUSTERED" {
+				info.clustering = "primary key"
+				if !primary {
+					info.clustering = "index " + name
+				}
+			}
+		}
+		i := &info.indexes[len(info.indexes)-1]
+		if included {
+			i.include = append(i.include, column)
+		} else {
+			i.columns = append(i.columns, column)
+		}
+		return nil
+	})
+	if err != nil {
+		return info, err
+	}
+
+	var stats, missing int64
+	err = db.QueryRowContext(ctx, fmt.Sprintf(`select
+			(select count(*) from sys.stats where object_id = object_id('%[1]s')),
+			(select count(*) from sys.stats as s where s.object_id = object_id('%[1]s') and stats_date(s.object_id, s.stats_id) is null),
+			(select coalesce(sum(rows), 0) from sys.partitions where object_id = object_id('%[1]s') and index_id in (0, 1))`, table)).Scan(&stats, &missing, &info.estimatedRows)
+	info.analyzed = stats > 0 && missing == 0
+	return info, err
+}
+
+func scanRows(ctx context.Context, db *sql.DB, query string, scan func(*sql.Rows) error) error {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		if err := scan(rows); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+var intDisplayWidth = regexp.MustCompile(`^(tinyint|smallint|int|bigint)\(\d+\)`)
+
+// canonicalType maps the type an engine reports to the type names of the
+// schema package, so equivalent types compare equal across engines. The
+// unsigned flag is kept, a signed and an unsigned column hold different ranges.
+func canonicalType(dataType string) string {
+	t := strings.ToLower(strings.TrimSpace(dataType))
+	if base, unsigned := strings.CutSuffix(t, " unsigned"); unsigned {
+		return canonicalType(base) + " unsigned"
+	}
+	t = intDisplayWidth.ReplaceAllString(t, "$1")
+
+	switch {
+	case t == "integer":
+		return "int"
+	case t == "text", t == "longtext", t == "varchar(max)", t == "nvarchar(max)":
+		return "text"
+	case t == "timestamp without time zone", t == "datetime", t == "datetime2":
+		return "timestamp"
+	case strings.HasPrefix(t, "character varying("):
+		return "varchar" + strings.TrimPrefix(t, "character varying")
+	case strings.HasPrefix(t, "character("):
+		return "char" + strings.TrimPrefix(t, "character")
+	case strings.HasPrefix(t, "numeric("):
+		return "decimal" + strings.TrimPrefix(t, "numeric")
+	case strings.HasPrefix(t, "datetime2("):
+		return "timestamp"
+	default:
+		return t
+	}
+}
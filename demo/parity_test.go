@@ -0,0 +1,61 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCanonicalType(t *testing.T) {
+	data := map[string]string{
+		"integer":                     "int",
+		"int(11)":                     "int",
+		"tinyint(4)":                  "tinyint",
+		"character varying(100)":      "varchar(100)",
+		"character(2)":                "char(2)",
+		"varchar(max)":                "text",
+		"longtext":                    "text",
+		"timestamp without time zone": "timestamp",
+		"datetime2":                   "timestamp",
+		"numeric(10,2)":               "decimal(10,2)",
+		"smallint":                    "smallint",
+		"int(10) unsigned":            "int unsigned",
+		"int unsigned":                "int unsigned",
+	}
+	for engineType, expected := range data {
+		if actual := canonicalType(engineType); actual != expected {
+			t.Errorf("canonicalType(%q) = %q, expected %q", engineType, actual, expected)
+		}
+	}
+}
+
+func TestTableProperties(t *testing.T) {
+	info := tableInfo{
+		exists:     true,
+		columns:    []columnInfo{{name: "id", dataType: "int"}, {name: "country", dataType: "char(2)", collation: "utf8mb4_0900_ai_ci", caseInsensitive: true}},
+		indexes:    []indexInfo{{name: "PRIMARY", columns: []string{"id"}, primary: true}, {name: "ix_country", columns: []string{"country"}, include: []string{"id"}}},
+		clustering: "primary key",
+		analyzed:   true,
+	}
+
+	var actual []string
+	for _, p := range tableProperties("client", info) {
+		actual = append(actual, p.name+": "+p.String())
+	}
+	expected := []string{
+		"client: present",
+		"client.id: int not null",
+		"client.country: char(2) not null",
+		"client.country collation: case insensitive (utf8mb4_0900_ai_ci)",
+		"client primary key: id",
+		"client clustering: primary key",
+		"client index ix_country: (country) include (id)",
+		"client statistics: analyzed (~0 rows)",
+	}
+	if !reflect.DeepEqual(actual, expected) {
+		t.Errorf("tableProperties() = %q, expected %q", actual, expected)
+	}
+
+	if actual := tableProperties("client", tableInfo{}); len(actual) != 1 || actual[0].value != "missing" {
+		t.Errorf("tableProperties() of a missing table = %v", actual)
+	}
+}
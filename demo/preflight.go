@@ -0,0 +1,79 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+
+	"github.com/solontsev/rdbms-performance-comparison/schema"
+)
+
+// tableCheck is a fixture table a test needs, it is verified on every target
+// before anything is timed.
+type tableCheck struct {
+	name string
+	rows int64
+	// indexes the queries of the test are about, optional
+	indexes []string
+}
+
+// preflight verifies the tables of a test and returns why the test can not run
+// on the target, or an empty string when the fixtures are in place.
+func preflight(ctx context.Context, db *sql.DB, engine string, tables []tableCheck) string {
+	for _, t := range tables {
+		exists, err := queryCount(ctx, db, tableExistsSql(engine, t.name))
+		if err != nil {
+			return fmt.Sprintf("unable to check table %s: %v", t.name, err)
+		}
+		if exists == 0 {
+			return fmt.Sprintf("table %s is missing", t.name)
+		}
+
+		rows, err := queryCount(ctx, db, fmt.Sprintf("select count(*) from %s", schema.QuoteIdent(engine, t.name)))
+		if err != nil {
+			return fmt.Sprintf("unable to count rows of %s: %v", t.name, err)
+		}
+		if rows != t.rows {
+			return fmt.Sprintf("%s has %d rows, expected %d", t.name, rows, t.rows)
+		}
+
+		for _, index := range t.indexes {
+			exists, err := queryCount(ctx, db, indexExistsSql(engine, t.name, index))
+			if err != nil {
+				return fmt.Sprintf("unable to check index %s: %v", index, err)
+			}
+			if exists == 0 {
+				return fmt.Sprintf("index %s on %s is missing", index, t.name)
+			}
+		}
+	}
+	return ""
+}
+
+func queryCount(ctx context.Context, db *sql.DB, query string) (int64, error) {
+	var count int64
+	err := db.QueryRowContext(ctx, query).Scan(&count)
+	return count, err
+}
+
+func tableExistsSql(engine string, table string) string {
+	currentSchema := "current_schema()"
+	switch engine {
+	case engineMySql, engineMariaDb:
+		currentSchema = "database()"
+	case engineMsSql:
+		currentSchema = "schema_name()"
+	}
+	return fmt.Sprintf("select count(*) from information_schema.tables where table_schema = %s and table_name = '%s'", currentSchema, table)
+}
+
+func indexExistsSql(engine string, table string, index string) string {
+	switch engine {
+	case engineMySql, engineMariaDb:
+		return fmt.Sprintf("select count(*) from information_schema.statistics where table_schema = database() and table_name = '%s' and index_name = '%s'", table, index)
+	case engineMsSql:
+		return fmt.Sprintf("select count(*) from sys.indexes where object_id = object_id('%s') and name = '%s'", table, index)
+	default:
+		return fmt.Sprintf("select count(*) from pg_indexes where schemaname = current_schema() and tablename = '%s' and indexname = '%s'", table, index)
+	}
+}
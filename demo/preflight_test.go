@@ -0,0 +1,33 @@
+package main
+
+import "testing"
+
+func TestTableChecksMatchFixtures(t *testing.T) {
+	for name, test := range Tests {
+		for _, check := range test.tables {
+			f := selectedFixtures(check.name)[0]
+			for _, index := range check.indexes {
+				found := false
+				for _, i := range f.table.Indexes {
+					found = found || i.Name == index
+				}
+				if !found {
+					t.Errorf("%s: fixture %s has no index %s", name, check.name, index)
+				}
+			}
+		}
+	}
+}
+
+func TestIndexExistsSql(t *testing.T) {
+	data := map[string]string{
+		enginePostgres: "select count(*) from pg_indexes where schemaname = current_schema() and tablename = 'client' and indexname = 'idx_client_country'",
+		engineMySql:    "select count(*) from information_schema.statistics where table_schema = database() and table_name = 'client' and index_name = 'idx_client_country'",
+		engineMsSql:    "select count(*) from sys.indexes where object_id = object_id('client') and name = 'idx_client_country'",
+	}
+	for engine, expected := range data {
+		if actual := indexExistsSql(engine, "client", "idx_client_country"); actual != expected {
+			t.Errorf("indexExistsSql(%s) = %q, expected %q", engine, actual, expected)
+		}
+	}
+}
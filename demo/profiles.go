@@ -0,0 +1,290 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/solontsev/rdbms-performance-comparison/harness"
+)
+
+var profilesFlag = flag.String("profiles", "", "comma separated server configuration profiles to run every target with, e.g. default,non-durable")
+
+// the profile that leaves the configuration of the server as it is
+const defaultProfile = "default"
+
+// serverProfile is a named server configuration. Settings are passed to the
+// server when -containers starts it and set at run time otherwise. MSSQL has
+// no command line settings, sp_configure sets them after the start.
+type serverProfile struct {
+	description string
+	// server settings by engine, values in a form both the command line and
+	// SET accept, e.g. bytes instead of 1G on MySQL
+	settings map[string]map[string]string
+	// statements by engine for options that are not server settings, they run
+	// on the database before the tests, undo after them
+	statements map[string][]string
+	undo       map[string][]string
+}
+
+var Profiles = map[string]serverProfile{
+	defaultProfile: {
+		description: "the configuration of the image",
+	},
+	"small-memory": {
+		description: "256MB of buffer pool, small sort and hash memory",
+		settings: map[string]map[string]string{
+			enginePostgres: {"shared_buffers": "256MB", "effective_cache_size": "768MB", "work_mem": "4MB"},
+			engineMySql:    {"innodb_buffer_pool_size": "268435456", "sort_buffer_size": "262144", "join_buffer_size": "262144"},
+			engineMariaDb:  {"innodb_buffer_pool_size": "268435456", "sort_buffer_size": "262144", "join_buffer_size": "262144"},
+			engineMsSql:    {"max server memory (MB)": "2048"},
+		},
+	},
+	"large-memory": {
+		description: "2GB of buffer pool, large sort and hash memory",
+		settings: map[string]map[string]string{
+			enginePostgres: {"shared_buffers": "2GB", "effective_cache_size": "6GB", "work_mem": "64MB"},
+			engineMySql:    {"innodb_buffer_pool_size": "2147483648", "sort_buffer_size": "67108864", "join_buffer_size": "67108864"},
+			engineMariaDb:  {"innodb_buffer_pool_size": "2147483648", "sort_buffer_size": "67108864", "join_buffer_size": "67108864"},
+			engineMsSql:    {"max server memory (MB)": "8192"},
+		},
+	},
+	"no-jit": {
+		description: "the JIT compilation of PostgreSQL is off",
+		settings: map[string]map[string]string{
+			enginePostgres: {"jit": "off"},
+		},
+	},
+	"non-durable": {
+		description: "commits do not wait for the log to be flushed",
+		settings: map[string]map[string]string{
+			enginePostgres: {"fsync": "off", "synchronous_commit": "off", "full_page_writes": "off"},
+			engineMySql:    {"innodb_flush_log_at_trx_commit": "0", "sync_binlog": "0"},
+			engineMariaDb:  {"innodb_flush_log_at_trx_commit": "0", "sync_binlog": "0"},
+		},
+		statements: map[string][]string{
+			engineMsSql: {"alter database current set delayed_durability = forced"},
+		},
+		undo: map[string][]string{
+			engineMsSql: {"alter database current set delayed_durability = disabled"},
+		},
+	},
+}
+
+// appliesTo reports whether a profile changes anything on an engine. The
+// default profile applies to every engine.
+func (p serverProfile) appliesTo(engine string) bool {
+	if len(p.settings) == 0 && len(p.statements) == 0 {
+		return true
+	}
+	_, hasSettings := p.settings[engine]
+	_, hasStatements := p.statements[engine]
+	return hasSettings || hasStatements
+}
+
+// expandProfiles returns a target per target and profile, named after both,
+// e.g. "pg-17.5 [non-durable]". A profile runs only on the engines it
+// configures.
+func expandProfiles(targets []target, names string) ([]target, error) {
+	if names == "" {
+		return targets, nil
+	}
+
+	var expanded []target
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		p, ok := Profiles[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown profile %q", name)
+		}
+		for _, t := range targets {
+			if !p.appliesTo(t.engine) {
+				continue
+			}
+			pt := t
+			pt.connectionName = profileName(t.connectionName, name)
+			pt.profile = name
+			pt.labels = map[string]string{"profile": name}
+			for k, v := range t.labels {
+				pt.labels[k] = v
+			}
+			if pt.queries == "" {
+				pt.queries = t.connectionName
+			}
+			expanded = append(expanded, pt)
+		}
+	}
+	return expanded, nil
+}
+
+// profilesCaption describes the profiles of the -profiles flag.
+func profilesCaption(names string) string {
+	var descriptions []string
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		descriptions = append(descriptions, fmt.Sprintf("%s: %s", name, Profiles[name].description))
+	}
+	return "profiles: " + strings.Join(descriptions, ", ")
+}
+
+func profileName(name string, profile string) string {
+	return fmt.Sprintf("%s [%s]", name, profile)
+}
+
+// profileArgs returns the arguments of the container command that apply the
+// settings of the profile of a target at the start of the server.
+func profileArgs(t target) []string {
+	settings := Profiles[t.profile].settings[t.engine]
+	var args []string
+	for _, name := range sortedKeys(settings) {
+		switch t.engine {
+		case enginePostgres:
+			args = append(args, "-c", name+"="+settings[name])
+		case engineMySql, engineMariaDb:
+			args = append(args, "--"+name+"="+settings[name])
+		}
+	}
+	if len(args) > 0 && t.engine == enginePostgres {
+		args = append([]string{"postgres"}, args...)
+	}
+	return args
+}
+
+// applyProfile configures the server and the database of a target for its
+// profile. The settings are set at run time when started is false or the
+// engine has no command line settings. It returns the function that brings
+// the previous configuration back on a pool and the settings that need a
+// restart.
+func applyProfile(ctx context.Context, db *sql.DB, t target, started bool) (func(*sql.DB), []string, error) {
+	p := Profiles[t.profile]
+	settings := p.settings[t.engine]
+	if started && t.engine != engineMsSql {
+		settings = nil
+	}
+
+	var undo []string
+	restore := func(db *sql.DB) {
+		// the configuration is brought back even when the run was interrupted
+		if err := harness.Exec(context.Background(), db, undo...); err != nil {
+			log.Printf("%s: unable to restore the configuration: %v", t.connectionName, err)
+		}
+	}
+
+	for _, name := range sortedKeys(settings) {
+		statements, previous, err := setSettingSql(ctx, db, t.engine, name, settings[name])
+		if err != nil {
+			return restore, nil, err
+		}
+		if err := harness.Exec(ctx, db, statements...); err != nil {
+			return restore, nil, err
+		}
+		undo = append(undo, previous...)
+	}
+
+	var pending []string
+	if t.engine == enginePostgres && len(settings) > 0 {
+		undo = append(undo, "select pg_reload_conf()")
+		if err := harness.Exec(ctx, db, "select pg_reload_conf()"); err != nil {
+			return restore, nil, err
+		}
+		err := scanRows(ctx, db, "select name from pg_settings where pending_restart", func(rows *sql.Rows) error {
+			var name string
+			if err := rows.Scan(&name); err != nil {
+				return err
+			}
+			pending = append(pending, name)
+			return nil
+		})
+		if err != nil {
+			return restore, nil, err
+		}
+	}
+
+	undo = append(undo, p.undo[t.engine]...)
+	return restore, pending, harness.Exec(ctx, db, p.statements[t.engine]...)
+}
+
+// setSettingSql returns the statements that change a server setting and the
+// ones that change it back to its previous value.
+func setSettingSql(ctx context.Context, db *sql.DB, engine string, name string, value string) ([]string, []string, error) {
+	switch engine {
+	case enginePostgres:
+		// a value an earlier alter system set is in postgresql.auto.conf, it is
+		// set again instead of being reset to the one of postgresql.conf
+		var previous []string
+		err := scanRows(ctx, db, fmt.Sprintf("select setting from pg_file_settings where name = '%s' and sourcefile like '%%postgresql.auto.conf' order by seqno desc limit 1", name), func(rows *sql.Rows) error {
+			var setting string
+			if err := rows.Scan(&setting); err != nil {
+				return err
+			}
+			previous = append(previous, setting)
+			return nil
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return []string{fmt.Sprintf("alter system set %s = '%s'", name, value)}, postgresUndoSql(name, previous), nil
+	case engineMySql, engineMariaDb:
+		var previous string
+		if err := db.QueryRowContext(ctx, fmt.Sprintf("select @@global.%s", name)).Scan(&previous); err != nil {
+			return nil, nil, err
+		}
+		return []string{fmt.Sprintf("set global %s = %s", name, settingValue(value))},
+			[]string{fmt.Sprintf("set global %s = %s", name, settingValue(previous))}, nil
+	case engineMsSql:
+		var previous, advanced string
+		if err := db.QueryRowContext(ctx, fmt.Sprintf("select cast(value as varchar(100)) from sys.configurations where name = '%s'", name)).Scan(&previous); err != nil {
+			return nil, nil, err
+		}
+		if err := db.QueryRowContext(ctx, "select cast(value as varchar(100)) from sys.configurations where name = 'show advanced options'").Scan(&advanced); err != nil {
+			return nil, nil, err
+		}
+		return msSqlConfigureSql(name, value, advanced), msSqlConfigureSql(name, previous, advanced), nil
+	default:
+		return nil, nil, fmt.Errorf("unknown engine %s", engine)
+	}
+}
+
+// postgresUndoSql returns the statement that brings a setting back to the
+// value an earlier alter system set, or resets it when there was none.
+func postgresUndoSql(name string, previous []string) []string {
+	if len(previous) == 0 {
+		return []string{fmt.Sprintf("alter system reset %s", name)}
+	}
+	return []string{fmt.Sprintf("alter system set %s = '%s'", name, strings.ReplaceAll(previous[0], "'", "''"))}
+}
+
+// msSqlConfigureSql returns the statements that set an advanced option with
+// sp_configure and then set show advanced options back to advanced.
+func msSqlConfigureSql(name string, value string, advanced string) []string {
+	return []string{
+		"exec sp_configure 'show advanced options', 1",
+		"reconfigure",
+		fmt.Sprintf("exec sp_configure '%s', %s", name, value),
+		"reconfigure",
+		fmt.Sprintf("exec sp_configure 'show advanced options', %s", advanced),
+		"reconfigure",
+	}
+}
+
+// settingValue quotes a value for SET unless it is a number.
+func settingValue(value string) string {
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return value
+	}
+	return "'" + value + "'"
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,83 @@
+package main
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExpandProfiles(t *testing.T) {
+	targets := []target{
+		{connectionName: PostgreSql17, engine: enginePostgres},
+		{connectionName: MySql9, engine: engineMySql},
+	}
+
+	expanded, err := expandProfiles(targets, "default, no-jit")
+	if err != nil {
+		t.Fatalf("expandProfiles() failed: %v", err)
+	}
+	expected := []string{"pg-17.5 [default]", "mysql-9.3.0 [default]", "pg-17.5 [no-jit]"}
+	if len(expanded) != len(expected) {
+		t.Fatalf("expandProfiles() = %v, expected %v", expanded, expected)
+	}
+	for i, name := range expected {
+		if expanded[i].connectionName != name || expanded[i].queries != targets[i%2].connectionName || expanded[i].labels["profile"] == "" {
+			t.Errorf("expandProfiles()[%d] = %+v, expected %s", i, expanded[i], name)
+		}
+	}
+
+	if _, err := expandProfiles(targets, "fast"); err == nil {
+		t.Errorf("expandProfiles() of an unknown profile did not fail")
+	}
+}
+
+func TestProfileArgs(t *testing.T) {
+	data := []struct {
+		target   target
+		expected string
+	}{
+		{target{engine: enginePostgres, profile: "non-durable"}, "postgres -c fsync=off -c full_page_writes=off -c synchronous_commit=off"},
+		{target{engine: engineMySql, profile: "small-memory"}, "--innodb_buffer_pool_size=268435456 --join_buffer_size=262144 --sort_buffer_size=262144"},
+		{target{engine: engineMsSql, profile: "small-memory"}, ""},
+		{target{engine: enginePostgres, profile: defaultProfile}, ""},
+		{target{engine: enginePostgres}, ""},
+	}
+
+	for _, d := range data {
+		if actual := strings.Join(profileArgs(d.target), " "); actual != d.expected {
+			t.Errorf("profileArgs(%s, %s) = %q, expected %q", d.target.engine, d.target.profile, actual, d.expected)
+		}
+	}
+}
+
+func TestProfilesAreValid(t *testing.T) {
+	for name, p := range Profiles {
+		if p.description == "" {
+			t.Errorf("profile %s has no description", name)
+		}
+		for engine := range p.settings {
+			for setting, value := range p.settings[engine] {
+				// SET GLOBAL does not accept sizes like 1G
+				if (engine == engineMySql || engine == engineMariaDb) && strings.HasSuffix(strings.ToUpper(value), "G") {
+					t.Errorf("profile %s sets %s of %s to %s, use bytes", name, setting, engine, value)
+				}
+			}
+		}
+	}
+}
+
+func TestUndoSql(t *testing.T) {
+	data := []struct {
+		actual   []string
+		expected string
+	}{
+		{postgresUndoSql("jit", nil), "alter system reset jit"},
+		{postgresUndoSql("work_mem", []string{"16MB"}), "alter system set work_mem = '16MB'"},
+		{msSqlConfigureSql("max server memory (MB)", "2147483647", "0"), "exec sp_configure 'show advanced options', 1; reconfigure; exec sp_configure 'max server memory (MB)', 2147483647; reconfigure; exec sp_configure 'show advanced options', 0; reconfigure"},
+	}
+
+	for _, d := range data {
+		if actual := strings.Join(d.actual, "; "); actual != d.expected {
+			t.Errorf("undo = %q, expected %q", actual, d.expected)
+		}
+	}
+}
@@ -1,10 +1,7 @@
 package main
 
 import (
-	"context"
-	"database/sql"
-	"log"
-	"time"
+	"github.com/solontsev/rdbms-performance-comparison/schema"
 )
 
 const MariaDb = "mariadb-11.8.2"
@@ -16,18 +13,39 @@ const PostgreSql18 = "pg-18-beta1"
 const MsSql22 = "mssql-22-CU19"
 const MsSql25 = "mssql-25-CTP2.0"
 
+// engines, used to pick engine specific syntax for generated query variants
+const engineMariaDb = schema.MariaDb
+const engineMySql = schema.MySql
+const enginePostgres = schema.PostgreSql
+const engineMsSql = schema.MsSql
+
 type testData struct {
 	testName  string
 	queries   map[string]map[string]string
-	f         func(context.Context, *sql.DB, string)
 	execCount int
+	// access paths to generate forced variants of every query for
+	hints []hint
+	// join algorithms to generate forced variants of every query for
+	joins []string
+	// session policy between executions, sessionReuse when empty
+	session string
+	// fixture tables the queries need, verified before the test runs
+	tables []tableCheck
+	// destructive tests modify the fixtures, the snapshot is restored before them
+	destructive bool
+	// dml is set for tests that modify data
+	dml *dmlTest
+	// hooks run on every target before and after the queries of the test,
+	// variantHooks on the session of a variant, by variant or query name
+	hooks        hooks
+	variantHooks map[string]hooks
 }
 
 var Tests = map[string]testData{
 	// access
 	"index-seek-vs-scan": {
-		"nonclustered index seek vs. scan",
-		map[string]map[string]string{
+		testName: "nonclustered index seek vs. scan",
+		queries: map[string]map[string]string{
 			//MySql8: {
 			//	"a - 1 row":     "select min(name) from client where country = 'UK';",
 			//	"b - 9 rows":    "select min(name) from client where country = 'NL';",
@@ -61,34 +79,29 @@ var Tests = map[string]testData{
 			//	"f - 7333 rows": "select min(name) from client where country >= 'US';",
 			//},
 			MsSql22: {
-				"a - 1 row":                 "select min(name) from client where country = 'UK';",
-				"b - 9 rows":                "select min(name) from client where country = 'NL';",
-				"c - 90 rows":               "select min(name) from client where country = 'FR';",
-				"c - 90 rows (forceseek)":   "select min(name) from client with (forceseek) where country = 'FR';",
-				"d - 900 rows":              "select min(name) from client where country = 'CY';",
-				"d - 900 rows (forceseek)":  "select min(name) from client with (forceseek) where country = 'CY';",
-				"e - 4000 rows":             "select min(name) from client where country = 'US';",
-				"e - 4000 rows (forceseek)": "select min(name) from client with (forceseek) where country = 'US';",
-				"f - 7333 rows":             "select min(name) from client where country >= 'US';",
+				"a - 1 row":     "select min(name) from client where country = 'UK';",
+				"b - 9 rows":    "select min(name) from client where country = 'NL';",
+				"c - 90 rows":   "select min(name) from client where country = 'FR';",
+				"d - 900 rows":  "select min(name) from client where country = 'CY';",
+				"e - 4000 rows": "select min(name) from client where country = 'US';",
+				"f - 7333 rows": "select min(name) from client where country >= 'US';",
 			},
 			//MsSql25: {
-			//	"a - 1 row":                 "select min(name) from client where country = 'UK';",
-			//	"b - 9 rows":                "select min(name) from client where country = 'NL';",
-			//	"c - 90 rows":               "select min(name) from client where country = 'FR';",
-			//	"c - 90 rows (forceseek)":   "select min(name) from client with (forceseek) where country = 'FR';",
-			//	"d - 900 rows":              "select min(name) from client where country = 'CY';",
-			//	"d - 900 rows (forceseek)":  "select min(name) from client with (forceseek) where country = 'CY';",
-			//	"e - 4000 rows":             "select min(name) from client where country = 'US';",
-			//	"e - 4000 rows (forceseek)": "select min(name) from client with (forceseek) where country = 'US';",
-			//	"f - 7333 rows":             "select min(name) from client where country >= 'US';",
+			//	"a - 1 row":     "select min(name) from client where country = 'UK';",
+			//	"b - 9 rows":    "select min(name) from client where country = 'NL';",
+			//	"c - 90 rows":   "select min(name) from client where country = 'FR';",
+			//	"d - 900 rows":  "select min(name) from client where country = 'CY';",
+			//	"e - 4000 rows": "select min(name) from client where country = 'US';",
+			//	"f - 7333 rows": "select min(name) from client where country >= 'US';",
 			//},
 		},
-		QueryString,
-		200,
+		execCount: 200,
+		tables:    []tableCheck{{name: "client", rows: 10000, indexes: []string{"idx_client_country"}}},
+		hints:     []hint{{kind: hintForceSeek, table: "client"}},
 	},
 	"index-seek-vs-scan-large": {
-		"nonclustered index seek vs. scan",
-		map[string]map[string]string{
+		testName: "nonclustered index seek vs. scan",
+		queries: map[string]map[string]string{
 			MySql9: {
 				"a - 100 row":      "select min(name) from client_large where country = 'UK';",
 				"b - 900 rows":     "select min(name) from client_large where country = 'NL';",
@@ -106,23 +119,21 @@ var Tests = map[string]testData{
 				"f - 733,333 rows": "select min(name) from client_large where country >= 'US';",
 			},
 			MsSql22: {
-				"a - 100 row":                  "select min(name) from client_large where country = 'UK';",
-				"b - 900 rows":                 "select min(name) from client_large where country = 'NL';",
-				"c - 9,000 rows":               "select min(name) from client_large where country = 'FR';",
-				"c - 9,000 rows (forceseek)":   "select min(name) from client_large with (forceseek) where country = 'FR';",
-				"d - 90,000 rows":              "select min(name) from client_large where country = 'CY';",
-				"d - 90,000 rows (forceseek)":  "select min(name) from client_large with (forceseek) where country = 'CY';",
-				"e - 400,000 rows":             "select min(name) from client_large where country = 'US';",
-				"e - 400,000 rows (forceseek)": "select min(name) from client_large with (forceseek) where country = 'US';",
-				"f - 733,333 rows":             "select min(name) from client_large where country >= 'US';",
+				"a - 100 row":      "select min(name) from client_large where country = 'UK';",
+				"b - 900 rows":     "select min(name) from client_large where country = 'NL';",
+				"c - 9,000 rows":   "select min(name) from client_large where country = 'FR';",
+				"d - 90,000 rows":  "select min(name) from client_large where country = 'CY';",
+				"e - 400,000 rows": "select min(name) from client_large where country = 'US';",
+				"f - 733,333 rows": "select min(name) from client_large where country >= 'US';",
 			},
 		},
-		QueryString,
-		5,
+		execCount: 5,
+		tables:    []tableCheck{{name: "client_large", rows: 1000000, indexes: []string{"idx_client_large_country"}}},
+		hints:     []hint{{kind: hintForceSeek, table: "client_large"}},
 	},
 	"clustered-index-seek-id": {
-		"clustered index seek",
-		map[string]map[string]string{
+		testName: "clustered index seek",
+		queries: map[string]map[string]string{
 			MySql9: {
 				"a - small": "select id from client where id = 5000;",
 				"b - large": "select id from client_large where id = 500000;",
@@ -136,12 +147,12 @@ var Tests = map[string]testData{
 				"b - large": "select id from client_large where id = 500000;",
 			},
 		},
-		QueryInt,
-		500,
+		execCount: 500,
+		tables:    []tableCheck{{name: "client", rows: 10000}, {name: "client_large", rows: 1000000}},
 	},
 	"clustered-index-seek-name": {
-		"clustered index seek",
-		map[string]map[string]string{
+		testName: "clustered index seek",
+		queries: map[string]map[string]string{
 			MySql9: {
 				"a - small": "select name from client where id = 5000;",
 				"b - large": "select name from client_large where id = 500000;",
@@ -155,12 +166,12 @@ var Tests = map[string]testData{
 				"b - large": "select name from client_large where id = 500000;",
 			},
 		},
-		QueryString,
-		500,
+		execCount: 500,
+		tables:    []tableCheck{{name: "client", rows: 10000}, {name: "client_large", rows: 1000000}},
 	},
 	"clustered-index-range": {
-		"clustered index range",
-		map[string]map[string]string{
+		testName: "clustered index range",
+		queries: map[string]map[string]string{
 			MySql8: {
 				"a - small":               "select min(name) from client where id >= 3000 and id < 5000;",
 				"b - large":               "select min(name) from client_large where id >= 300000 and id < 500000",
@@ -192,12 +203,12 @@ var Tests = map[string]testData{
 				"c - large - small range": "select min(name) from client_large where id >= 300000 and id < 320000",
 			},
 		},
-		QueryString,
-		30,
+		execCount: 30,
+		tables:    []tableCheck{{name: "client", rows: 10000}, {name: "client_large", rows: 1000000}},
 	},
 	"table-scan": {
-		"",
-		map[string]map[string]string{
+		testName: "",
+		queries: map[string]map[string]string{
 			MySql9: {
 				"a - tinyint - 10%": "select count(*) from filter_1m where status_id_tinyint = 0;",
 				"a - tinyint - 90%": "select count(*) from filter_1m where status_id_tinyint = 1;",
@@ -235,13 +246,13 @@ var Tests = map[string]testData{
 				"e - text - 90%":    "select count(*) from filter_1m where status_text = 'active';",
 			},
 		},
-		QueryInt,
-		10,
+		execCount: 10,
+		tables:    []tableCheck{{name: "filter_1m", rows: 1000000}},
 	},
 
 	"dml": {
-		"postgres index only scan behaviour",
-		map[string]map[string]string{
+		testName: "postgres index only scan behaviour",
+		queries: map[string]map[string]string{
 			MySql9: {
 				"index only scan":              "select min(ts), max(description) from (select ts, description from transactions where ts < '2020-01-01 01:00:00') as t;",
 				"index only scan after update": "select min(ts), max(description) from (select ts, description from transactions_modified where ts < '2020-01-01 01:00:00') as t;",
@@ -257,14 +268,89 @@ var Tests = map[string]testData{
 				"index only scan after update": "select min(ts), max(description) from (select ts, description from transactions_modified where ts < '2020-01-01 01:00:00') as t;",
 				"index scan after update":      "select min(ts), max(description) from (select ts, description from transactions_wo_covered_index where ts < '2020-01-01 01:00:00') as t;",
 			}},
-		QueryTsAndString,
-		100,
+		execCount: 100,
+		// fresh statistics everywhere, the visibility map of transactions is set
+		// by vacuum, the modified tables keep theirs stale
+		hooks: hooks{setup: map[string][]string{
+			enginePostgres: {"vacuum analyze transactions", "analyze transactions_modified", "analyze transactions_wo_covered_index"},
+			engineMySql:    {"analyze table transactions", "analyze table transactions_modified", "analyze table transactions_wo_covered_index"},
+			engineMsSql:    {"update statistics transactions", "update statistics transactions_modified", "update statistics transactions_wo_covered_index"},
+		}},
+		tables: []tableCheck{
+			{name: "transactions", rows: 1000000, indexes: []string{"ix_ts_description"}},
+			{name: "transactions_modified", rows: 1000000, indexes: []string{"ix_transactions_modified__ts_description"}},
+			{name: "transactions_wo_covered_index", rows: 1000000, indexes: []string{"ix_transactions_wo_covered_index__ts"}},
+		},
+	},
+
+	"write-rollback": {
+		testName: "insert, update and delete in a rolled back transaction",
+		queries: map[string]map[string]string{
+			MySql9: {
+				"a - insert 1,000 rows": "insert into transactions_dml (id, description, ts) select id + 100000000, description, ts from transactions_dml where id <= 1000;",
+				"b - update 1,000 rows": "update transactions_dml set description = 'updated' where id <= 1000;",
+				"c - delete 1,000 rows": "delete from transactions_dml where id <= 1000;",
+			},
+			PostgreSql17: {
+				"a - insert 1,000 rows": "insert into transactions_dml (id, description, ts) select id + 100000000, description, ts from transactions_dml where id <= 1000;",
+				"b - update 1,000 rows": "update transactions_dml set description = 'updated' where id <= 1000;",
+				"c - delete 1,000 rows": "delete from transactions_dml where id <= 1000;",
+			},
+			MsSql22: {
+				"a - insert 1,000 rows": "insert into transactions_dml (id, description, ts) select id + 100000000, description, ts from transactions_dml where id <= 1000;",
+				"b - update 1,000 rows": "update transactions_dml set description = 'updated' where id <= 1000;",
+				"c - delete 1,000 rows": "delete from transactions_dml where id <= 1000;",
+			},
+		},
+		execCount: 20,
+		tables:    []tableCheck{{name: "transactions_dml", rows: 1000000, indexes: []string{"ix_transactions_dml__ts"}}},
+		dml:       &dmlTest{rollback: true},
+	},
+	"write-commit": {
+		testName: "insert, update and delete committed and reset",
+		queries: map[string]map[string]string{
+			MySql9: {
+				"a - insert 1,000 rows": "insert into transactions_dml (id, description, ts) select id + 100000000, description, ts from transactions_dml where id <= 1000;",
+				"b - update 1,000 rows": "update transactions_dml set description = 'updated' where id <= 1000;",
+				"c - delete 1,000 rows": "delete from transactions_dml where id <= 1000;",
+			},
+			PostgreSql17: {
+				"a - insert 1,000 rows": "insert into transactions_dml (id, description, ts) select id + 100000000, description, ts from transactions_dml where id <= 1000;",
+				"b - update 1,000 rows": "update transactions_dml set description = 'updated' where id <= 1000;",
+				"c - delete 1,000 rows": "delete from transactions_dml where id <= 1000;",
+			},
+			MsSql22: {
+				"a - insert 1,000 rows": "insert into transactions_dml (id, description, ts) select id + 100000000, description, ts from transactions_dml where id <= 1000;",
+				"b - update 1,000 rows": "update transactions_dml set description = 'updated' where id <= 1000;",
+				"c - delete 1,000 rows": "delete from transactions_dml where id <= 1000;",
+			},
+		},
+		execCount:   20,
+		tables:      []tableCheck{{name: "transactions_dml", rows: 1000000, indexes: []string{"ix_transactions_dml__ts"}}, {name: "transactions", rows: 1000000}},
+		destructive: true,
+		dml: &dmlTest{reset: map[string]map[string]string{
+			MySql9: {
+				"a - insert 1,000 rows": "delete from transactions_dml where id > 100000000;",
+				"b - update 1,000 rows": "update transactions_dml set description = (select t.description from transactions as t where t.id = transactions_dml.id) where id <= 1000;",
+				"c - delete 1,000 rows": "insert into transactions_dml (id, description, ts) select id, description, ts from transactions where id <= 1000;",
+			},
+			PostgreSql17: {
+				"a - insert 1,000 rows": "delete from transactions_dml where id > 100000000;",
+				"b - update 1,000 rows": "update transactions_dml set description = (select t.description from transactions as t where t.id = transactions_dml.id) where id <= 1000;",
+				"c - delete 1,000 rows": "insert into transactions_dml (id, description, ts) select id, description, ts from transactions where id <= 1000;",
+			},
+			MsSql22: {
+				"a - insert 1,000 rows": "delete from transactions_dml where id > 100000000;",
+				"b - update 1,000 rows": "update transactions_dml set description = (select t.description from transactions as t where t.id = transactions_dml.id) where id <= 1000;",
+				"c - delete 1,000 rows": "insert into transactions_dml (id, description, ts) select id, description, ts from transactions where id <= 1000;",
+			},
+		}},
 	},
 
 	// skip scan
 	"distinct-count": {
-		"select distinct / count distinct",
-		map[string]map[string]string{
+		testName: "select distinct / count distinct",
+		queries: map[string]map[string]string{
 			MariaDb: {
 				"a": "select count(distinct a) as cnt from group_by_table",
 				"b": "select count(distinct b) as cnt from group_by_table",
@@ -305,12 +391,12 @@ var Tests = map[string]testData{
 				"b": "select count(distinct b) as cnt from group_by_table",
 				"c": "select count(distinct c) as cnt from group_by_table",
 			}},
-		QueryInt,
-		20,
+		execCount: 20,
+		tables:    []tableCheck{{name: "group_by_table", rows: 1000000}},
 	},
 	"distinct-count-ex": {
-		"select distinct / count distinct",
-		map[string]map[string]string{
+		testName: "select distinct / count distinct",
+		queries: map[string]map[string]string{
 			MySql9: {
 				"a": "select count(distinct a) as cnt from group_by_table",
 				"b": "select count(distinct b) as cnt from group_by_table",
@@ -322,9 +408,12 @@ var Tests = map[string]testData{
 				//"b-sequential": "set max_parallel_workers_per_gather = 1; select count(distinct b) as cnt from group_by_table",
 				"c": "select count(distinct c) as cnt from group_by_table",
 				//"c-sequential": "set max_parallel_workers_per_gather = 1; select count(distinct c) as cnt from group_by_table",
-				"a-recursive": "with recursive t as (select min(a) as x from group_by_table union all select (select min(a) from group_by_table where a > t.x) from t where t.x is not null) select count(*) from (select x from t where x is not null union all select null where exists (select 1 from group_by_table where a is null)) as tmp;",
-				"b-recursive": "with recursive t as (select min(b) as x from group_by_table union all select (select min(b) from group_by_table where b > t.x) from t where t.x is not null) select count(*) from (select x from t where x is not null union all select null where exists (select 1 from group_by_table where b is null)) as tmp;",
-				"c-recursive": "with recursive t as (select min(c) as x from group_by_table union all select (select min(c) from group_by_table where c > t.x) from t where t.x is not null) select count(*) from (select x from t where x is not null union all select null where exists (select 1 from group_by_table where c is null)) as tmp;",
+				"a-recursive":  "with recursive t as (select min(a) as x from group_by_table union all select (select min(a) from group_by_table where a > t.x) from t where t.x is not null) select count(*) from (select x from t where x is not null union all select null where exists (select 1 from group_by_table where a is null)) as tmp;",
+				"b-recursive":  "with recursive t as (select min(b) as x from group_by_table union all select (select min(b) from group_by_table where b > t.x) from t where t.x is not null) select count(*) from (select x from t where x is not null union all select null where exists (select 1 from group_by_table where b is null)) as tmp;",
+				"c-recursive":  "with recursive t as (select min(c) as x from group_by_table union all select (select min(c) from group_by_table where c > t.x) from t where t.x is not null) select count(*) from (select x from t where x is not null union all select null where exists (select 1 from group_by_table where c is null)) as tmp;",
+				"a-temp-table": "create temp table result (x int); do $$ declare cur int; begin select min(a) into cur from group_by_table; while cur is not null loop insert into result values (cur); select min(a) into cur from group_by_table where a > cur; end loop; end $$; select count(*) from result;",
+				"b-temp-table": "create temp table result (x int); do $$ declare cur int; begin select min(b) into cur from group_by_table; while cur is not null loop insert into result values (cur); select min(b) into cur from group_by_table where b > cur; end loop; end $$; select count(*) from result;",
+				"c-temp-table": "create temp table result (x int); do $$ declare cur int; begin select min(c) into cur from group_by_table; while cur is not null loop insert into result values (cur); select min(c) into cur from group_by_table where c > cur; end loop; end $$; select count(*) from result;",
 			},
 			MsSql22: {
 				"a":               "select count(distinct a) as cnt from group_by_table",
@@ -338,12 +427,13 @@ var Tests = map[string]testData{
 				"c-numbers-table": "with min_max as (select min(c) as min_c, max(c) as max_c from group_by_table), possible_values as (select n.id from numbers as n inner join min_max as mm on n.id >= mm.min_c and n.id <= mm.max_c), result as (select pv.id from possible_values as pv where exists (select top (1) 1 from group_by_table as g where g.c = pv.id)) select count(*) from result;",
 			},
 		},
-		QueryInt,
-		20,
+		execCount: 20,
+		tables:    []tableCheck{{name: "group_by_table", rows: 1000000}, {name: "numbers", rows: 10000}},
+		session:   sessionReset,
 	},
 	"skip-scan-1": {
-		"skip scan more complex  example",
-		map[string]map[string]string{
+		testName: "skip scan more complex  example",
+		queries: map[string]map[string]string{
 			MySql9: {
 				"default": "select min(min_c2) from (select c1, min(c2) as min_c2 from large_group_by_table group by c1) as t",
 			},
@@ -364,12 +454,12 @@ var Tests = map[string]testData{
 				"super-super-optimised": "select min(t3.min_c2) from (select 0 as c1 union all select 1 union all select 2 union all select 3 union all select 4 union all select 5 union all select 6 union all select 7 union all select 8 union all select 9) as t cross apply (select min(t2.c2) as min_c2 from large_group_by_table as t2 where t2.c1 = t.c1) as t3;",
 			},
 		},
-		QueryInt,
-		0,
+		execCount: 0,
+		tables:    []tableCheck{{name: "large_group_by_table", rows: 1000000, indexes: []string{"idx_large_group_by_table_c1_c2_c3_c4"}}, {name: "numbers", rows: 10000}},
 	},
 	"skip-scan-2": {
-		"",
-		map[string]map[string]string{
+		testName: "",
+		queries: map[string]map[string]string{
 			MySql9: {
 				"default": "select count(*) from skip_scan_example where b = 0;",
 			},
@@ -383,13 +473,13 @@ var Tests = map[string]testData{
 				"default": "select count(*) from skip_scan_example where b = 0;",
 			},
 		},
-		QueryInt,
-		30,
+		execCount: 30,
+		tables:    []tableCheck{{name: "skip_scan_example", rows: 1000000, indexes: []string{"idx_skip_scan_example_a_b"}}},
 	},
 
 	"index-merge-opt": {
-		"index seek with complex condition",
-		map[string]map[string]string{
+		testName: "index seek with complex condition",
+		queries: map[string]map[string]string{
 			MySql9: {
 				"a - default":                 "select count(*) from client where id >= 1 and id < 10000 and id < 2;",
 				"b - bigger range":            "select count(*) from order_detail where order_id >= 1 and order_id < 10000 and order_id < 2;",
@@ -409,43 +499,36 @@ var Tests = map[string]testData{
 				"d - changed predicate order": "select count(*) from order_detail where order_id >= 1 and order_id < 2 and order_id < 100000;",
 			},
 		},
-		QueryInt,
-		200,
+		execCount: 200,
+		tables:    []tableCheck{{name: "client", rows: 10000}, {name: "order_detail", rows: 1000000}},
 	},
 	"join-agg": {
-		"join and aggregate 2 sorted tables",
-		map[string]map[string]string{
+		testName: "join and aggregate 2 sorted tables",
+		queries: map[string]map[string]string{
 			MySql8: {
-				"default":         "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od.price) as total_price from `order` as o inner join order_detail as od on od.order_id = o.id group by o.id) as tmp;",
-				"force hash join": "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od.price) as total_price from `order` as o ignore index (primary) inner join order_detail as od ignore index (primary) on od.order_id = o.id group by o.id) as tmp;",
-				"extra pre-agg":   "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od_agg.price) as total_price from `order` as o inner join (select od.order_id, sum(od.price) as price from order_detail as od group by od.order_id) as od_agg on od_agg.order_id = o.id group by o.id) as tmp;",
+				"default":       "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od.price) as total_price from `order` as o inner join order_detail as od on od.order_id = o.id group by o.id) as tmp;",
+				"extra pre-agg": "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od_agg.price) as total_price from `order` as o inner join (select od.order_id, sum(od.price) as price from order_detail as od group by od.order_id) as od_agg on od_agg.order_id = o.id group by o.id) as tmp;",
 			},
 			MySql9: {
-				"default":         "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od.price) as total_price from `order` as o inner join order_detail as od on od.order_id = o.id group by o.id) as tmp;",
-				"force hash join": "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od.price) as total_price from `order` as o ignore index (primary) inner join order_detail as od ignore index (primary) on od.order_id = o.id group by o.id) as tmp;",
-				"extra pre-agg":   "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od_agg.price) as total_price from `order` as o inner join (select od.order_id, sum(od.price) as price from order_detail as od group by od.order_id) as od_agg on od_agg.order_id = o.id group by o.id) as tmp;",
+				"default":       "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od.price) as total_price from `order` as o inner join order_detail as od on od.order_id = o.id group by o.id) as tmp;",
+				"extra pre-agg": "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od_agg.price) as total_price from `order` as o inner join (select od.order_id, sum(od.price) as price from order_detail as od group by od.order_id) as od_agg on od_agg.order_id = o.id group by o.id) as tmp;",
 			},
 			PostgreSql17: {
 				"default":       "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od.price) as total_price from \"order\" as o inner join order_detail as od on od.order_id = o.id group by o.id) as tmp;",
 				"extra pre-agg": "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od_agg.price) as total_price from \"order\" as o inner join (select od.order_id, sum(od.price) as price from order_detail as od group by od.order_id) as od_agg on od_agg.order_id = o.id group by o.id) as tmp;",
 			},
 			MsSql22: {
-				"default":                       "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od.price) as total_price from [order] as o inner join order_detail as od on od.order_id = o.id group by o.id) as tmp;",
-				"default seq":                   "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od.price) as total_price from [order] as o inner join order_detail as od on od.order_id = o.id group by o.id) as tmp option (maxdop 1);",
-				"extra pre-agg":                 "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od_agg.price) as total_price from [order] as o inner join (select od.order_id, sum(od.price) as price from order_detail as od group by od.order_id) as od_agg on od_agg.order_id = o.id group by o.id) as tmp;",
-				"merge join":                    "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od.price) as total_price from [order] as o inner merge join order_detail as od on od.order_id = o.id group by o.id) as tmp;",
-				"extra pre-agg with merge join": "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od_agg.price) as total_price from [order] as o inner join (select od.order_id, sum(od.price) as price from order_detail as od group by od.order_id) as od_agg on od_agg.order_id = o.id group by o.id) as tmp;",
-
-				//"loop join":            "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od.price) as total_price from [order] as o inner loop join order_detail as od on od.order_id = o.id group by o.id) as tmp;",
-				//"loop join (maxdop 1)": "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od.price) as total_price from [order] as o inner loop join order_detail as od on od.order_id = o.id group by o.id) as tmp option (maxdop 1);",
+				"default":       "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od.price) as total_price from [order] as o inner join order_detail as od on od.order_id = o.id group by o.id) as tmp;",
+				"extra pre-agg": "select min(order_id), sum(total_price) from (select o.id as order_id, sum(od_agg.price) as total_price from [order] as o inner join (select od.order_id, sum(od.price) as price from order_detail as od group by od.order_id) as od_agg on od_agg.order_id = o.id group by o.id) as tmp;",
 			},
 		},
-		QueryIntAndFloat64,
-		5,
+		execCount: 5,
+		tables:    []tableCheck{{name: "order", rows: 100000}, {name: "order_detail", rows: 1000000}},
+		joins:     []string{joinNestedLoop, joinHash, joinMerge},
 	},
 	"join-partial-agg": {
-		"grouping with partial aggregation",
-		map[string]map[string]string{
+		testName: "grouping with partial aggregation",
+		queries: map[string]map[string]string{
 			MySql8: {
 				"small": "select min(cnt) as a, min(name) as b from (select p.name, count(*) as cnt from `order` as o inner join group_by_table as l on l.id = o.id inner join product as p on p.id = l.c group by p.name) as t;",
 				"big":   "select min(cnt) as a, min(name) as b from (select p.name, count(*) as cnt from `order` as o inner join group_by_table as l on l.id = o.id inner join product as p on p.id = l.a group by p.name) as t;",
@@ -461,12 +544,12 @@ var Tests = map[string]testData{
 				"big":   "select min(cnt) as a, min(name) as b from (select p.name, count(*) as cnt from [order] as o inner join group_by_table as l on l.id = o.id inner join product as p on p.id = l.a group by p.name) as t;",
 			},
 		},
-		QueryIntAndString,
-		15,
+		execCount: 15,
+		tables:    []tableCheck{{name: "order", rows: 100000}, {name: "group_by_table", rows: 1000000}, {name: "product", rows: 1000000}},
 	},
 	"combine-index": {
-		"combine select from 2 indexes",
-		map[string]map[string]string{
+		testName: "combine select from 2 indexes",
+		queries: map[string]map[string]string{
 			MySql8: {
 				"a - simple":       "select count(*) from large_group_by_table as l where l.c2 = 1 and l.c3 = 1;",
 				"b - complex":      "select count(*) from large_group_by_table as l where (l.c2 = 1 or l.c2 = 2 or l.c2 = 50) and l.c3 = 1;",
@@ -514,13 +597,54 @@ var Tests = map[string]testData{
 				//"x2":           "select count(*)\nfrom large_group_by_table as l\nwhere l.c2 >= 0 and l.c2 < 22 and l.c3 = 1;",
 			},
 		},
-		QueryInt,
-		300,
+		execCount: 300,
+		tables:    []tableCheck{{name: "large_group_by_table", rows: 1000000, indexes: []string{"idx_large_group_by_table_c2", "idx_large_group_by_table_c3"}}},
+	},
+
+	// statistics
+	"skew": {
+		testName: "filter skewed, correlated and nullable columns",
+		queries: map[string]map[string]string{
+			MySql9: {
+				"a - uniform":                    "select max(uniform_id) from skewed where uniform_id = 500;",
+				"b - zipf - frequent value":      "select max(uniform_id) from skewed where zipf_id = 1;",
+				"c - zipf - rare value":          "select max(uniform_id) from skewed where zipf_id = 1000;",
+				"d - correlated columns":         "select max(uniform_id) from skewed where zipf_id = 1 and correlated_id = 1;",
+				"e - nulls":                      "select max(uniform_id) from skewed where nullable_id is null;",
+				"f - hot range":                  "select max(uniform_id) from skewed where hot_id between 1 and 10;",
+				"g - cold range":                 "select max(uniform_id) from skewed where hot_id between 50000 and 50010;",
+				"h - skewed labels - rare value": "select max(uniform_id) from skewed where status = 'archived';",
+			},
+			PostgreSql17: {
+				"a - uniform":                    "select max(uniform_id) from skewed where uniform_id = 500;",
+				"b - zipf - frequent value":      "select max(uniform_id) from skewed where zipf_id = 1;",
+				"c - zipf - rare value":          "select max(uniform_id) from skewed where zipf_id = 1000;",
+				"d - correlated columns":         "select max(uniform_id) from skewed where zipf_id = 1 and correlated_id = 1;",
+				"e - nulls":                      "select max(uniform_id) from skewed where nullable_id is null;",
+				"f - hot range":                  "select max(uniform_id) from skewed where hot_id between 1 and 10;",
+				"g - cold range":                 "select max(uniform_id) from skewed where hot_id between 50000 and 50010;",
+				"h - skewed labels - rare value": "select max(uniform_id) from skewed where status = 'archived';",
+			},
+			MsSql22: {
+				"a - uniform":                    "select max(uniform_id) from skewed where uniform_id = 500;",
+				"b - zipf - frequent value":      "select max(uniform_id) from skewed where zipf_id = 1;",
+				"c - zipf - rare value":          "select max(uniform_id) from skewed where zipf_id = 1000;",
+				"d - correlated columns":         "select max(uniform_id) from skewed where zipf_id = 1 and correlated_id = 1;",
+				"e - nulls":                      "select max(uniform_id) from skewed where nullable_id is null;",
+				"f - hot range":                  "select max(uniform_id) from skewed where hot_id between 1 and 10;",
+				"g - cold range":                 "select max(uniform_id) from skewed where hot_id between 50000 and 50010;",
+				"h - skewed labels - rare value": "select max(uniform_id) from skewed where status = 'archived';",
+			},
+		},
+		execCount: 20,
+		tables: []tableCheck{{name: "skewed", rows: 1000000, indexes: []string{
+			"idx_skewed_zipf_id", "idx_skewed_correlated_id", "idx_skewed_nullable_id", "idx_skewed_hot_id", "idx_skewed_status",
+		}}},
 	},
 
 	//"needs-refactoring-00-3": {
-	//	"count rows in parallel",
-	//	map[string]map[string]string{
+	//	testName: "count rows in parallel",
+	//	queries: map[string]map[string]string{
 	//		MySql8: {
 	//			"w/o pk":  "select count(*) from filter_1m;",
 	//			"pk":      "select count(*) from filter_1m_with_pk;",
@@ -538,12 +662,11 @@ var Tests = map[string]testData{
 	//			"pk - id": "select count(id) from filter_1m_with_pk;",
 	//		},
 	//	},
-	//	QueryInt,
-	//	10,
+	//	execCount: 10,
 	//},
 	//"needs-refactoring-01": {
-	//	"lookup by primary key",
-	//	map[string]map[string]string{
+	//	testName: "lookup by primary key",
+	//	queries: map[string]map[string]string{
 	//		MySql8: {
 	//			"first key": "select id from client as c where id = 0;",
 	//			//"middle key":                        "select id from client as c where id = 5000;",
@@ -569,12 +692,11 @@ var Tests = map[string]testData{
 	//			"lookup_and_agg": "select count(*) from order_detail as od where order_id = 1;",
 	//		},
 	//	},
-	//	QueryInt,
-	//	3000,
+	//	execCount: 3000,
 	//},
 	//"needs-refactoring-02": {
-	//	"lookup by primary key + column not in index",
-	//	map[string]map[string]string{
+	//	testName: "lookup by primary key + column not in index",
+	//	queries: map[string]map[string]string{
 	//		MySql8: {
 	//			"": "select id, name from client as c where id = 1;",
 	//		},
@@ -588,12 +710,11 @@ var Tests = map[string]testData{
 	//			"": "select id, name from client as c where id = 1;",
 	//		},
 	//	},
-	//	QueryIntAndString,
-	//	3000,
+	//	execCount: 3000,
 	//},
 	//"needs-refactoring-03": {
-	//	"min and max",
-	//	map[string]map[string]string{
+	//	testName: "min and max",
+	//	queries: map[string]map[string]string{
 	//		MySql8: {
 	//			"min":     "select min(id) from client as c;",
 	//			"max":     "select min(id) from client as c;",
@@ -615,92 +736,6 @@ var Tests = map[string]testData{
 	//			"min-max": "select min(id) + max(id) from client as c;",
 	//		},
 	//	},
-	//	QueryInt,
-	//	3000,
+	//	execCount: 3000,
 	//},
 }
-
-func QueryInt(ctx context.Context, db *sql.DB, query string) {
-	ctx, cancel := context.WithTimeout(ctx, 120*time.Second)
-	defer cancel()
-
-	// execute query with context and handle no rows error
-
-	var i int
-	if err := db.QueryRowContext(ctx, query).Scan(&i); err != nil {
-		if err == sql.ErrNoRows {
-			i = -1
-		} else {
-			log.Fatalf("unable to execute query: %v", err)
-		}
-	}
-	//log.Println("result = ", result)
-}
-
-func QueryString(ctx context.Context, db *sql.DB, query string) {
-	ctx, cancel := context.WithTimeout(ctx, 120*time.Second)
-	defer cancel()
-
-	// execute query with context and handle no rows error
-
-	var s string
-	if err := db.QueryRowContext(ctx, query).Scan(&s); err != nil {
-		if err == sql.ErrNoRows {
-			s = "N/A"
-		} else {
-			log.Fatalf("unable to execute query: %v", err)
-		}
-	}
-	//log.Println("result = ", result)
-}
-
-func QueryIntAndString(ctx context.Context, db *sql.DB, query string) {
-	ctx, cancel := context.WithTimeout(ctx, 120*time.Second)
-	defer cancel()
-
-	var i int
-	var s string
-	if err := db.QueryRowContext(ctx, query).Scan(&i, &s); err != nil {
-		if err == sql.ErrNoRows {
-			i = -1
-			s = "N/A"
-		} else {
-			log.Fatalf("unable to execute query: %v", err)
-		}
-	}
-	//log.Println("result = ", result)
-}
-
-func QueryTsAndString(ctx context.Context, db *sql.DB, query string) {
-	ctx, cancel := context.WithTimeout(ctx, 120*time.Second)
-	defer cancel()
-
-	var t time.Time
-	var s string
-	if err := db.QueryRowContext(ctx, query).Scan(&t, &s); err != nil {
-		if err == sql.ErrNoRows {
-			t = time.Now()
-			s = "N/A"
-		} else {
-			log.Fatalf("unable to execute query: %v", err)
-		}
-	}
-	//log.Println("result = ", result)
-}
-
-func QueryIntAndFloat64(ctx context.Context, db *sql.DB, query string) {
-	ctx, cancel := context.WithTimeout(ctx, 120*time.Second)
-	defer cancel()
-
-	var i int
-	var f float64
-	if err := db.QueryRowContext(ctx, query).Scan(&i, &f); err != nil {
-		if err == sql.ErrNoRows {
-			i = -1
-			f = 0.0
-		} else {
-			log.Fatalf("unable to execute query: %v", err)
-		}
-	}
-	//log.Println("result = ", result)
-}
@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+
+	"github.com/docker/go-units"
+	"github.com/solontsev/rdbms-performance-comparison/harness"
+)
+
+// resourcesFlag parses the limits of every container the runner starts
+var resourcesFlag = harness.ResourceFlags(flag.CommandLine)
+
+// the minimum memory of the MSSQL image, it does not start with less
+const msSqlMinMemory = 2 << 30
+
+// startWarning returns a warning when the memory limit is too small for the
+// engine of a target to start at all.
+func startWarning(r harness.Resources, t target) string {
+	if r.Memory > 0 && t.engine == engineMsSql && r.Memory < msSqlMinMemory {
+		return fmt.Sprintf("%s is less than the %s MSSQL needs", units.BytesSize(float64(r.Memory)), units.BytesSize(msSqlMinMemory))
+	}
+	return ""
+}
+
+// the max server memory of MSSQL when it is not set, the server then sizes
+// itself from the memory limit of its container
+const msSqlDefaultMaxMemory = 2147483647 << 20
+
+// memoryWarning returns a warning when the memory the engine is configured to
+// use does not fit into the memory limit of its container.
+func memoryWarning(r harness.Resources, engine string, memory int64) string {
+	if r.Memory == 0 || memory <= r.Memory {
+		return ""
+	}
+	if engine == engineMsSql && memory == msSqlDefaultMaxMemory {
+		return ""
+	}
+	setting, _ := engineMemorySql(engine)
+	return fmt.Sprintf("%s of %s exceeds the container limit of %s", setting, units.BytesSize(float64(memory)), units.BytesSize(float64(r.Memory)))
+}
+
+// engineMemorySql returns the main memory setting of an engine and the query
+// of its value in bytes, as the server uses it: the image default or the
+// value of the profile.
+func engineMemorySql(engine string) (string, string) {
+	switch engine {
+	case enginePostgres:
+		return "shared_buffers", "select pg_size_bytes(current_setting('shared_buffers'))"
+	case engineMySql, engineMariaDb:
+		return "innodb_buffer_pool_size", "select @@global.innodb_buffer_pool_size"
+	case engineMsSql:
+		return "max server memory", "select cast(value_in_use as bigint) * 1048576 from sys.configurations where name = 'max server memory (MB)'"
+	}
+	return "", ""
+}
+
+// checkMemory compares the memory setting of the running server of a target
+// with the memory limit and returns a warning when it does not fit.
+func checkMemory(ctx context.Context, db *sql.DB, t target, r harness.Resources) (string, error) {
+	_, query := engineMemorySql(t.engine)
+	if r.Memory == 0 || query == "" {
+		return "", nil
+	}
+	var memory int64
+	if err := db.QueryRowContext(ctx, query).Scan(&memory); err != nil {
+		return "", err
+	}
+	return memoryWarning(r, t.engine, memory), nil
+}
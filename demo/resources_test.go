@@ -0,0 +1,36 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/solontsev/rdbms-performance-comparison/harness"
+)
+
+func TestMemoryWarning(t *testing.T) {
+	limits := harness.Resources{Memory: 1 << 30}
+	data := []struct {
+		engine   string
+		memory   int64
+		expected string
+	}{
+		{enginePostgres, 256 << 20, ""},
+		{enginePostgres, 2 << 30, "shared_buffers of 2GiB exceeds the container limit of 1GiB"},
+		{engineMySql, 2 << 30, "innodb_buffer_pool_size of 2GiB exceeds the container limit of 1GiB"},
+		{engineMsSql, 2 << 30, "max server memory of 2GiB exceeds the container limit of 1GiB"},
+		// the default max server memory of MSSQL is not set, the server sizes itself from the limit
+		{engineMsSql, 2147483647 << 20, ""},
+	}
+
+	for _, d := range data {
+		if actual := memoryWarning(limits, d.engine, d.memory); actual != d.expected {
+			t.Errorf("memoryWarning(%s, %d) = %q, expected %q", d.engine, d.memory, actual, d.expected)
+		}
+	}
+
+	if actual, expected := startWarning(limits, target{engine: engineMsSql}), "1GiB is less than the 2GiB MSSQL needs"; actual != expected {
+		t.Errorf("startWarning(mssql) = %q, expected %q", actual, expected)
+	}
+	if actual := memoryWarning(harness.Resources{}, enginePostgres, 2<<30); actual != "" {
+		t.Errorf("memoryWarning() without a limit = %q", actual)
+	}
+}
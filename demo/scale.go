@@ -0,0 +1,177 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+var scaleFlag = flag.String("scale", "", "comma separated scale factors of the fixture tables, e.g. 0.1,1,10")
+
+// parseScales parses the -scale flag, the scale factors are sorted and
+// deduplicated. Without the flag the tables are used as they are.
+func parseScales(value string) ([]float64, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	seen := make(map[float64]struct{})
+	var scales []float64
+	for _, s := range strings.Split(value, ",") {
+		sf, err := strconv.ParseFloat(strings.TrimSpace(s), 64)
+		if err != nil || sf <= 0 {
+			return nil, fmt.Errorf("invalid scale factor %q", s)
+		}
+		if _, ok := seen[sf]; !ok {
+			seen[sf] = struct{}{}
+			scales = append(scales, sf)
+		}
+	}
+	sort.Float64s(scales)
+	return scales, nil
+}
+
+func formatScale(sf float64) string {
+	return strconv.FormatFloat(sf, 'f', -1, 64)
+}
+
+// scaledTable returns the name of a table at a scale factor, scale 1 keeps
+// the original name, so the tests work without scaling.
+func scaledTable(name string, sf float64) string {
+	if sf == 1 {
+		return name
+	}
+	return fmt.Sprintf("%s_sf%s", name, strings.ReplaceAll(formatScale(sf), ".", "_"))
+}
+
+func scaledRows(rows int64, sf float64) int64 {
+	return int64(math.Round(float64(rows) * sf))
+}
+
+// scalable reports whether a table is a fixture that grows with the scale
+// factor. Helper tables like numbers keep their size.
+func scalable(table string) bool {
+	for _, f := range fixtures {
+		if f.table.Name == table {
+			return !f.fixed
+		}
+	}
+	return false
+}
+
+// scaled returns the fixture at a scale factor. Its tables and indexes get a
+// suffix, the data comes from the seed of the original table, so a smaller
+// scale is a prefix of a larger one.
+func (f fixture) scaled(sf float64) fixture {
+	if f.fixed || sf == 1 {
+		return f
+	}
+
+	name := f.table.Name
+	rename := regexp.MustCompile(`\b` + regexp.QuoteMeta(name) + `\b`)
+
+	scaled := f
+	if scaled.base == "" {
+		scaled.base = name
+	}
+	scaled.rows = int(scaledRows(int64(f.rows), sf))
+	scaled.table.Name = scaledTable(name, sf)
+	scaled.table.Indexes = nil
+	for _, index := range f.table.Indexes {
+		index.Name = scaledTable(index.Name, sf)
+		scaled.table.Indexes = append(scaled.table.Indexes, index)
+	}
+	scaled.after = make(map[string][]string, len(f.after))
+	for engine, statements := range f.after {
+		for _, s := range statements {
+			scaled.after[engine] = append(scaled.after[engine], rename.ReplaceAllString(s, scaled.table.Name))
+		}
+	}
+	return scaled
+}
+
+// table references of queries, DML and maintenance statements like
+// "analyze table t" or "update statistics t"
+var statementTableRefPattern = regexp.MustCompile("(?i)\\b(?:from|join|into|update|table|vacuum|analyze)\\s+(?:(?:table|analyze|full|statistics)\\s+)*[`\"\\[]?(\\w+)")
+
+// scaleQuery points the fixture tables a query reads or writes to their
+// tables at a scale factor.
+func scaleQuery(query string, sf float64) string {
+	// 0 is the scale of variants that are not scaled
+	if sf == 1 || sf == 0 {
+		return query
+	}
+
+	matches := statementTableRefPattern.FindAllStringSubmatchIndex(query, -1)
+	for i := len(matches) - 1; i >= 0; i-- {
+		start, end := matches[i][2], matches[i][3]
+		if scalable(query[start:end]) {
+			query = query[:start] + scaledTable(query[start:end], sf) + query[end:]
+		}
+	}
+	return query
+}
+
+// scaleTest returns the test with its table checks and hints at a scale factor.
+func scaleTest(test testData, sf float64) testData {
+	if sf == 1 {
+		return test
+	}
+
+	tables := make([]tableCheck, len(test.tables))
+	for i, t := range test.tables {
+		if scalable(t.name) {
+			t = tableCheck{name: scaledTable(t.name, sf), rows: scaledRows(t.rows, sf), indexes: make([]string, len(t.indexes))}
+			for j, index := range test.tables[i].indexes {
+				t.indexes[j] = scaledTable(index, sf)
+			}
+		}
+		tables[i] = t
+	}
+	test.tables = tables
+
+	hints := make([]hint, len(test.hints))
+	for i, h := range test.hints {
+		if scalable(h.table) {
+			h.table = scaledTable(h.table, sf)
+		}
+		hints[i] = h
+	}
+	test.hints = hints
+
+	return test
+}
+
+func scaleVariantName(name string, sf float64) string {
+	return fmt.Sprintf("%s [sf %s]", name, formatScale(sf))
+}
+
+var scaleSuffix = regexp.MustCompile(`^(.*) \[sf ([\d.]+)\](.*)$`)
+
+// addGrowth notes how the time of every variant grows compared to the
+// smallest scale. The exponent is the slope on a log-log scale: 1 is linear,
+// above 1 the engine degrades super-linearly with the data size.
+func addGrowth(cells map[string]cell, scales []float64) {
+	first := scales[0]
+	for name, c := range cells {
+		m := scaleSuffix.FindStringSubmatch(name)
+		if m == nil || c.duration == 0 {
+			continue
+		}
+		sf, _ := strconv.ParseFloat(m[2], 64)
+		if sf == first {
+			continue
+		}
+		base, ok := cells[m[1]+" [sf "+formatScale(first)+"]"+m[3]]
+		if !ok || base.duration == 0 {
+			continue
+		}
+		growth := float64(c.duration) / float64(base.duration)
+		exponent := math.Log(growth) / math.Log(sf/first)
+		cells[name] = c.withNote(fmt.Sprintf("x%.2f, n^%.2f", growth, exponent))
+	}
+}
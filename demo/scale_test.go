@@ -0,0 +1,93 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseScales(t *testing.T) {
+	scales, err := parseScales("10, 0.1,1,10")
+	if err != nil || !reflect.DeepEqual(scales, []float64{0.1, 1, 10}) {
+		t.Errorf("parseScales() = %v, %v", scales, err)
+	}
+	if _, err := parseScales("0"); err == nil {
+		t.Error("parseScales(0) did not fail")
+	}
+	if scales, err := parseScales(""); scales != nil || err != nil {
+		t.Errorf("parseScales(\"\") = %v, %v", scales, err)
+	}
+}
+
+func TestScaleQuery(t *testing.T) {
+	data := []struct {
+		query    string
+		sf       float64
+		expected string
+	}{
+		{"select min(name) from client where country = 'UK';", 10, "select min(name) from client_sf10 where country = 'UK';"},
+		{"select id from `order` as o join order_detail as od on od.order_id = o.id order by o.id", 0.1, "select id from `order_sf0_1` as o join order_detail_sf0_1 as od on od.order_id = o.id order by o.id"},
+		{"select n.id from numbers as n join client as c on c.id = n.id", 2, "select n.id from numbers as n join client_sf2 as c on c.id = n.id"},
+		{"select id from client", 1, "select id from client"},
+		{"insert into transactions_dml (id) select id from transactions", 2, "insert into transactions_dml_sf2 (id) select id from transactions_sf2"},
+		{"update transactions_modified set description = description", 2, "update transactions_modified_sf2 set description = description"},
+		{"vacuum analyze transactions", 2, "vacuum analyze transactions_sf2"},
+		{"analyze table transactions", 2, "analyze table transactions_sf2"},
+		{"update statistics transactions", 2, "update statistics transactions_sf2"},
+	}
+
+	for _, d := range data {
+		if actual := scaleQuery(d.query, d.sf); actual != d.expected {
+			t.Errorf("scaleQuery(%q, %v) = %q, expected %q", d.query, d.sf, actual, d.expected)
+		}
+	}
+}
+
+func TestScaleTest(t *testing.T) {
+	test := testData{
+		tables: []tableCheck{{name: "client", rows: 10000, indexes: []string{"idx_client_country"}}, {name: "numbers", rows: 10000}},
+		hints:  []hint{{kind: hintForceSeek, table: "client"}},
+	}
+
+	scaled := scaleTest(test, 10)
+	expected := []tableCheck{{name: "client_sf10", rows: 100000, indexes: []string{"idx_client_country_sf10"}}, {name: "numbers", rows: 10000}}
+	if !reflect.DeepEqual(scaled.tables, expected) {
+		t.Errorf("scaleTest() tables = %v, expected %v", scaled.tables, expected)
+	}
+	if scaled.hints[0].table != "client_sf10" || test.hints[0].table != "client" {
+		t.Errorf("scaleTest() hints = %v, original %v", scaled.hints, test.hints)
+	}
+}
+
+func TestScaledFixture(t *testing.T) {
+	f := selectedFixtures("transactions_modified")[0].scaled(0.5)
+	if f.table.Name != "transactions_modified_sf0_5" || f.rows != 500000 || f.table.Indexes[0].Name != "ix_transactions_modified__ts_description_sf0_5" {
+		t.Errorf("scaled() = %s, %d rows, %v", f.table.Name, f.rows, f.table.Indexes)
+	}
+	if expected := "update transactions_modified_sf0_5 set description = description"; f.after[engineMsSql][0] != expected {
+		t.Errorf("scaled() after = %q, expected %q", f.after[engineMsSql][0], expected)
+	}
+}
+
+func TestAddGrowth(t *testing.T) {
+	cells := map[string]cell{
+		"a [sf 1]":          {duration: 10 * time.Millisecond},
+		"a [sf 10]":         {duration: 100 * time.Millisecond},
+		"a [sf 100]":        {duration: 10 * time.Second},
+		"b [sf 1] [dop 2]":  {duration: 10 * time.Millisecond},
+		"b [sf 10] [dop 2]": {duration: 20 * time.Millisecond},
+	}
+	addGrowth(cells, []float64{1, 10, 100})
+
+	expected := map[string]string{
+		"a [sf 1]":          "",
+		"a [sf 10]":         "x10.00, n^1.00",
+		"a [sf 100]":        "x1000.00, n^1.50",
+		"b [sf 10] [dop 2]": "x2.00, n^0.30",
+	}
+	for name, note := range expected {
+		if cells[name].note != note {
+			t.Errorf("addGrowth() %s note = %q, expected %q", name, cells[name].note, note)
+		}
+	}
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// driverDsn adds the connection parameters the runner relies on to the DSN
+// of a target.
+func driverDsn(t target) string {
+	if t.driverName != "mysql" || strings.Contains(t.dsn, "multiStatements=") {
+		return t.dsn
+	}
+	// scripts are sent as a single query, the driver rejects them by default
+	if strings.Contains(t.dsn, "?") {
+		return t.dsn + "&multiStatements=true"
+	}
+	return t.dsn + "?multiStatements=true"
+}
+
+var tempTablePatterns = map[string]*regexp.Regexp{
+	engineMsSql:    regexp.MustCompile(`(?i)\bcreate\s+table\s+(#\w+)`),
+	enginePostgres: regexp.MustCompile(`(?i)\bcreate\s+(?:local\s+)?temp(?:orary)?\s+table\s+(?:if\s+not\s+exists\s+)?(\w+)`),
+	engineMySql:    regexp.MustCompile(`(?i)\bcreate\s+temporary\s+table\s+(?:if\s+not\s+exists\s+)?(\w+)`),
+	engineMariaDb:  regexp.MustCompile(`(?i)\bcreate\s+temporary\s+table\s+(?:if\s+not\s+exists\s+)?(\w+)`),
+}
+
+// tempTableCleanup returns the statements that drop the temporary tables a
+// script creates, so the next execution on the same session can create them
+// again.
+func tempTableCleanup(engine string, query string) []string {
+	pattern, ok := tempTablePatterns[engine]
+	if !ok {
+		return nil
+	}
+
+	var statements []string
+	for _, m := range pattern.FindAllStringSubmatch(query, -1) {
+		switch engine {
+		case engineMySql, engineMariaDb:
+			statements = append(statements, fmt.Sprintf("drop temporary table if exists %s", m[1]))
+		default:
+			statements = append(statements, fmt.Sprintf("drop table if exists %s", m[1]))
+		}
+	}
+	return statements
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestTempTableCleanup(t *testing.T) {
+	data := []struct {
+		engine   string
+		query    string
+		expected []string
+	}{
+		{engineMsSql, "create table #result (x int); select count(*) from #result;", []string{"drop table if exists #result"}},
+		{enginePostgres, "create temp table result (x int); select count(*) from result;", []string{"drop table if exists result"}},
+		{engineMySql, "create temporary table if not exists result (x int); select 1;", []string{"drop temporary table if exists result"}},
+		{engineMySql, "select count(*) from client;", nil},
+	}
+
+	for _, d := range data {
+		if actual := tempTableCleanup(d.engine, d.query); !reflect.DeepEqual(actual, d.expected) {
+			t.Errorf("tempTableCleanup(%s, %q) = %v, expected %v", d.engine, d.query, actual, d.expected)
+		}
+	}
+}
+
+func TestDriverDsn(t *testing.T) {
+	data := []struct {
+		t        target
+		expected string
+	}{
+		{target{driverName: "mysql", dsn: "root:mysql@tcp(127.0.0.1:3307)/test_db"}, "root:mysql@tcp(127.0.0.1:3307)/test_db?multiStatements=true"},
+		{target{driverName: "mysql", dsn: "root:mysql@tcp(127.0.0.1:3307)/test_db?parseTime=true"}, "root:mysql@tcp(127.0.0.1:3307)/test_db?parseTime=true&multiStatements=true"},
+		{target{driverName: "postgres", dsn: "postgres://localhost/test_db"}, "postgres://localhost/test_db"},
+	}
+
+	for _, d := range data {
+		if actual := driverDsn(d.t); actual != d.expected {
+			t.Errorf("driverDsn(%q) = %q, expected %q", d.t.dsn, actual, d.expected)
+		}
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+var rowLabelPattern = regexp.MustCompile(`(?i)\b(\d[\d,]*)\s+rows?\b`)
+
+// labeledRows returns the number of rows the name of a query claims the
+// predicate qualifies, e.g. 90000 for "d - 90,000 rows".
+func labeledRows(name string) (int64, bool) {
+	m := rowLabelPattern.FindStringSubmatch(name)
+	if m == nil {
+		return 0, false
+	}
+	rows, err := strconv.ParseInt(strings.ReplaceAll(m[1], ",", ""), 10, 64)
+	return rows, err == nil
+}
+
+var fromKeyword = regexp.MustCompile(`(?i)\bfrom\b`)
+
+// clauses that change the number of rows a select list is computed over
+var rowShapingClause = regexp.MustCompile(`(?i)\b(?:group\s+by|having|distinct|union|limit|offset|fetch|top)\b`)
+
+// countQuery rewrites a single select into one counting the rows that
+// qualify for its predicate. Queries with subqueries, grouping or row limits
+// are not rewritten.
+func countQuery(query string) (string, bool) {
+	q := strings.TrimSuffix(strings.TrimSpace(query), ";")
+	if !strings.HasPrefix(strings.ToLower(q), "select") || strings.Contains(q, ";") {
+		return "", false
+	}
+	if len(selectKeyword.FindAllStringIndex(q, -1)) != 1 || rowShapingClause.MatchString(q) {
+		return "", false
+	}
+	from := fromKeyword.FindStringIndex(q)
+	if from == nil {
+		return "", false
+	}
+	return "select count(*) " + q[from[0]:], true
+}
+
+// verifyLabel counts the rows the predicate of a labeled query qualifies and
+// returns a note when the label does not match the data of the target.
+func verifyLabel(ctx context.Context, db *sql.DB, name string, query string) (string, error) {
+	expected, ok := labeledRows(name)
+	if !ok {
+		return "", nil
+	}
+	count, ok := countQuery(query)
+	if !ok {
+		return "", nil
+	}
+
+	actual, err := queryCount(ctx, db, count)
+	if err != nil {
+		return "", err
+	}
+	if actual != expected {
+		return fmt.Sprintf("label: %d rows, data: %d rows", expected, actual), nil
+	}
+	return "", nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import "testing"
+
+func TestLabeledRows(t *testing.T) {
+	data := []struct {
+		name     string
+		rows     int64
+		labelled bool
+	}{
+		{"a - 1 row", 1, true},
+		{"d - 90,000 rows", 90000, true},
+		{"e - 4000 rows [dop 2]", 4000, true},
+		{"b - large", 0, false},
+		{"a - tinyint - 10%", 0, false},
+	}
+
+	for _, d := range data {
+		rows, labelled := labeledRows(d.name)
+		if rows != d.rows || labelled != d.labelled {
+			t.Errorf("labeledRows(%q) = %d, %v, expected %d, %v", d.name, rows, labelled, d.rows, d.labelled)
+		}
+	}
+}
+
+func TestCountQuery(t *testing.T) {
+	data := []struct {
+		query    string
+		expected string
+	}{
+		{"select min(name) from client where country = 'UK';", "select count(*) from client where country = 'UK'"},
+		{"SELECT id, name\nFROM client_large WHERE country >= 'US'", "select count(*) FROM client_large WHERE country >= 'US'"},
+		{"select min(c) from (select c from t) as x", ""},
+		{"select count(distinct a) from t", ""},
+		{"select c1, min(c2) from t group by c1", ""},
+		{"drop table if exists t; select 1 from t", ""},
+		{"with t as (select 1 as c) select c from t", ""},
+	}
+
+	for _, d := range data {
+		actual, ok := countQuery(d.query)
+		if actual != d.expected || ok != (d.expected != "") {
+			t.Errorf("countQuery(%q) = %q, %v, expected %q", d.query, actual, ok, d.expected)
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+
+	"github.com/solontsev/rdbms-performance-comparison/harness"
+)
+
+// session policies, how the connection is prepared between executions of a variant
+const sessionReuse = "reuse" // keep the session and everything the previous execution left in it
+const sessionReset = "reset" // reset the session state on the same connection
+const sessionFresh = "fresh" // open a new connection for every execution
+
+// session is the pinned connection a variant is executed on. Depending on
+// the policy it is reset or replaced between executions.
+type session struct {
+	db     *sql.DB
+	conn   *sql.Conn
+	engine string
+	policy string
+	setup  []string
+}
+
+func openSession(ctx context.Context, db *sql.DB, engine string, policy string, setup []string) *session {
+	s := &session{db: db, engine: engine, policy: effectiveSessionPolicy(engine, policy), setup: setup}
+	s.connect(ctx)
+	return s
+}
+
+// effectiveSessionPolicy returns the policy that is applied on the engine.
+// The MySQL driver does not expose COM_RESET_CONNECTION and there is no SQL
+// statement for it, so a reset falls back to a fresh connection.
+func effectiveSessionPolicy(engine string, policy string) string {
+	if policy == "" {
+		return sessionReuse
+	}
+	if policy == sessionReset && (engine == engineMySql || engine == engineMariaDb) {
+		return sessionFresh
+	}
+	return policy
+}
+
+func sessionPolicyCaption(policy string) string {
+	if policy == "" {
+		policy = sessionReuse
+	}
+	caption := fmt.Sprintf("session policy: %s", policy)
+	if policy == sessionReset {
+		caption += fmt.Sprintf(" (%s and %s: %s)", engineMySql, engineMariaDb, sessionFresh)
+	}
+	return caption
+}
+
+func (s *session) connect(ctx context.Context) {
+	var err error
+	s.conn, err = s.db.Conn(ctx)
+	if err != nil {
+		fatalf("Unable to get connection: %v", err)
+	}
+	// the connection is established lazily, do it before the measurement
+	if err := s.conn.PingContext(ctx); err != nil {
+		fatalf("Unable to connect to database: %v", err)
+	}
+	if err := harness.Exec(ctx, s.conn, s.setup...); err != nil {
+		fatalf("unable to execute %v", err)
+	}
+}
+
+// prepare applies the session policy after an execution.
+func (s *session) prepare(ctx context.Context) {
+	switch s.policy {
+	case sessionReset:
+		if s.engine == engineMsSql {
+			// the driver resets a connection it gets back from the pool with
+			// sp_reset_connection, sent along with the next request
+			s.conn.Close()
+			s.connect(ctx)
+			return
+		}
+		if err := harness.Exec(ctx, s.conn, append([]string{"discard all"}, s.setup...)...); err != nil {
+			fatalf("unable to execute %v", err)
+		}
+	case sessionFresh:
+		// returning driver.ErrBadConn makes database/sql close the connection
+		// instead of putting it back to the pool
+		_ = s.conn.Raw(func(any) error { return driver.ErrBadConn })
+		s.conn.Close()
+		s.connect(ctx)
+	}
+}
+
+func (s *session) Close() error {
+	return s.conn.Close()
+}
+
+func (s *session) QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error) {
+	return s.conn.QueryContext(ctx, query, args...)
+}
+
+func (s *session) QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row {
+	return s.conn.QueryRowContext(ctx, query, args...)
+}
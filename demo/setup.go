@@ -0,0 +1,271 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"log"
+	"math/rand"
+	"strings"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	mssql "github.com/microsoft/go-mssqldb"
+	"github.com/solontsev/rdbms-performance-comparison/harness"
+	"github.com/solontsev/rdbms-performance-comparison/schema"
+)
+
+var seed = flag.Int64("seed", 1, "seed of the fixture data generator, the same seed gives the same data on every engine")
+var fixtureNames = flag.String("fixtures", "", "comma separated tables to create with the setup command, all when empty")
+
+// setupFixtures creates the fixture tables on every target and loads them.
+func setupFixtures(ctx context.Context) {
+	selected := selectedFixtures(*fixtureNames)
+	scales, err := parseScales(*scaleFlag)
+	if err != nil {
+		fatalf("Error: %v", err)
+	}
+	if scales == nil {
+		scales = []float64{1}
+	}
+
+	// the targets of the profiles of a server share its database
+	loaded := make(map[string]struct{})
+	for _, d := range databases {
+		if _, ok := loaded[d.dsn]; ok {
+			continue
+		}
+		loaded[d.dsn] = struct{}{}
+
+		if d.engine == engineMsSql {
+			createMsSqlDatabase(ctx, d)
+		}
+
+		db := openTarget(ctx, d)
+		if d.engine == engineMySql || d.engine == engineMariaDb {
+			// LOAD DATA LOCAL is disabled on the server by default
+			if _, err := db.ExecContext(ctx, "set global local_infile = 1"); err != nil {
+				fatalf("%s: unable to enable local_infile: %v", d.connectionName, err)
+			}
+		}
+
+		for i, sf := range scales {
+			for _, f := range selected {
+				if f.fixed && i > 0 {
+					continue
+				}
+				f = f.scaled(sf)
+				start := time.Now()
+				rows, err := createFixture(ctx, db, d.engine, f)
+				if err != nil {
+					fatalf("%s: unable to create %s: %v", d.connectionName, f.table.Name, err)
+				}
+				log.Printf("%s: %s, %d rows loaded in %s", d.connectionName, f.table.Name, rows, time.Since(start).Round(time.Millisecond))
+			}
+		}
+
+		db.Close()
+		// the snapshot of the previous data is taken again before the next
+		// destructive test
+		if err := dropSnapshot(ctx, d); err != nil {
+			fatalf("%s: unable to drop the snapshot: %v", d.connectionName, err)
+		}
+	}
+}
+
+// snapshot takes the snapshot of a target that destructive tests restore.
+func snapshot(ctx context.Context, t target) {
+	start := time.Now()
+	if err := takeSnapshot(ctx, t); err != nil {
+		fatalf("%s: unable to take a snapshot: %v", t.connectionName, err)
+	}
+	log.Printf("%s: snapshot taken in %s", t.connectionName, time.Since(start).Round(time.Millisecond))
+}
+
+// printDdl prints the DDL of the fixture tables for every engine, followed by
+// the places where an engine deviates from the table description.
+func printDdl() {
+	selected := selectedFixtures(*fixtureNames)
+
+	var compromises []schema.Compromise
+	for _, engine := range schema.Engines {
+		fmt.Printf("-- %s\n", engine)
+		for _, f := range selected {
+			ddl := schema.Generate(engine, f.table)
+			for _, s := range ddl.Statements() {
+				fmt.Printf("%s;\n", s)
+			}
+			compromises = append(compromises, ddl.Compromises...)
+		}
+		fmt.Println()
+	}
+
+	if len(compromises) > 0 {
+		fmt.Println("-- compromises")
+		for _, c := range compromises {
+			fmt.Printf("-- %s\n", c)
+		}
+	}
+}
+
+func selectedFixtures(names string) []fixture {
+	if names == "" {
+		return fixtures
+	}
+
+	var selected []fixture
+	for _, name := range strings.Split(names, ",") {
+		found := false
+		for _, f := range fixtures {
+			if f.table.Name == strings.TrimSpace(name) {
+				selected = append(selected, f)
+				found = true
+			}
+		}
+		if !found {
+			fatalf("Unknown fixture %q", name)
+		}
+	}
+	return selected
+}
+
+// createMsSqlDatabase creates the database of the DSN, the SQL Server image
+// does not create one on startup.
+func createMsSqlDatabase(ctx context.Context, t target) {
+	db, database, err := openMaintenance(ctx, t)
+	if err != nil {
+		fatalf("Invalid DSN of %s: %v", t.connectionName, err)
+	}
+	defer db.Close()
+
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("if db_id('%s') is null create database %s", database, schema.QuoteIdent(engineMsSql, database))); err != nil {
+		fatalf("%s: unable to create database %s: %v", t.connectionName, database, err)
+	}
+}
+
+func createFixture(ctx context.Context, db *sql.DB, engine string, f fixture) (int, error) {
+	ddl := schema.Generate(engine, f.table)
+	if err := harness.Exec(ctx, db, ddl.Create...); err != nil {
+		return 0, err
+	}
+
+	rows, err := bulkLoad(ctx, db, engine, f)
+	if err != nil {
+		return rows, err
+	}
+
+	return rows, harness.Exec(ctx, db, append(ddl.Finish, f.after[engine]...)...)
+}
+
+// fixtureRand returns the generator of a table. It depends on the table name,
+// so the data does not change when fixtures are added, removed or reordered.
+func fixtureRand(table string) *rand.Rand {
+	h := fnv.New64a()
+	h.Write([]byte(table))
+	return rand.New(rand.NewSource(*seed ^ int64(h.Sum64())))
+}
+
+// bulkLoad loads the generated rows with the bulk load path of the engine:
+// COPY for PostgreSQL, LOAD DATA LOCAL INFILE for MySQL and MariaDB and the
+// bulk copy protocol for MSSQL.
+func bulkLoad(ctx context.Context, db *sql.DB, engine string, f fixture) (int, error) {
+	columns := f.table.ColumnNames()
+
+	switch engine {
+	case engineMySql, engineMariaDb:
+		return loadData(ctx, db, f, columns)
+	case engineMsSql:
+		return copyIn(ctx, db, f, mssql.CopyIn(schema.QuoteIdent(engineMsSql, f.table.Name), mssql.BulkOptions{Tablock: true}, columns...))
+	default:
+		return copyIn(ctx, db, f, pq.CopyIn(f.table.Name, columns...))
+	}
+}
+
+// copyIn loads the rows with a driver bulk copy statement, both lib/pq and
+// go-mssqldb buffer the rows passed to Exec and send them on the final Exec.
+func copyIn(ctx context.Context, db *sql.DB, f fixture, copyStatement string) (int, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	stmt, err := tx.PrepareContext(ctx, copyStatement)
+	if err != nil {
+		return 0, err
+	}
+
+	rows := 0
+	f.generate(f.rows, f.rand(), func(values ...any) {
+		if err != nil {
+			return
+		}
+		_, err = stmt.ExecContext(ctx, values...)
+		rows++
+	})
+	if err != nil {
+		return rows, err
+	}
+	if _, err := stmt.ExecContext(ctx); err != nil {
+		return rows, err
+	}
+	if err := stmt.Close(); err != nil {
+		return rows, err
+	}
+
+	return rows, tx.Commit()
+}
+
+// loadData streams the rows to LOAD DATA LOCAL INFILE as tab separated values.
+func loadData(ctx context.Context, db *sql.DB, f fixture, columns []string) (int, error) {
+	reader, writer := io.Pipe()
+	handler := "fixture_" + f.table.Name
+	mysql.RegisterReaderHandler(handler, func() io.Reader { return reader })
+	defer mysql.DeregisterReaderHandler(handler)
+
+	rows := 0
+	go func() {
+		var err error
+		f.generate(f.rows, f.rand(), func(values ...any) {
+			if err != nil {
+				return
+			}
+			fields := make([]string, len(values))
+			for i, v := range values {
+				fields[i] = tsvValue(v)
+			}
+			_, err = io.WriteString(writer, strings.Join(fields, "\t")+"\n")
+			rows++
+		})
+		writer.CloseWithError(err)
+	}()
+
+	query := fmt.Sprintf("load data local infile 'Reader::%s' into table %s character set utf8mb4 fields terminated by '\\t' escaped by '\\\\' lines terminated by '\\n' (%s)",
+		handler, schema.QuoteIdent(engineMySql, f.table.Name), schema.QuoteIdents(engineMySql, columns))
+	_, err := db.ExecContext(ctx, query)
+	// unblock the generator when the server stopped reading
+	reader.Close()
+
+	return rows, err
+}
+
+var tsvEscaper = strings.NewReplacer("\\", "\\\\", "\t", "\\t", "\n", "\\n")
+
+func tsvValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "\\N"
+	case string:
+		return tsvEscaper.Replace(v)
+	case time.Time:
+		return v.Format("2006-01-02 15:04:05")
+	case float64:
+		return fmt.Sprintf("%.2f", v)
+	default:
+		return fmt.Sprint(v)
+	}
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"math/rand"
+	"testing"
+)
+
+func TestFixturesAreDeterministic(t *testing.T) {
+	collect := func() []any {
+		var values []any
+		orderDetails(1000000, fixtureRand("order_detail"), func(v ...any) {
+			if len(values) < 100 {
+				values = append(values, v...)
+			}
+		})
+		return values
+	}
+
+	first, second := collect(), collect()
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("value %d differs between runs: %v != %v", i, first[i], second[i])
+		}
+	}
+	if rand.New(rand.NewSource(*seed)).Int63() == fixtureRand("order_detail").Int63() {
+		t.Error("fixture generator does not depend on the table name")
+	}
+}
@@ -0,0 +1,387 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/go-sql-driver/mysql"
+	"github.com/solontsev/rdbms-performance-comparison/harness"
+	"github.com/solontsev/rdbms-performance-comparison/schema"
+)
+
+// the snapshot of a database is kept next to it, under the name with the suffix
+const snapshotSuffix = "_snapshot"
+
+// the directory in the container of a MySQL or MariaDB server the tablespace
+// files of the snapshots are copied to, outside of the data directory
+const tablespaceSnapshots = "/var/tmp/snapshots"
+
+// snapshotRestore is the result row with the restore time, it is not part of
+// the time of the queries
+const snapshotRestore = "(snapshot restore)"
+
+// withDatabase returns the target connected to another database on the same
+// server and the name of the database the target uses.
+func withDatabase(t target, database string) (target, string, error) {
+	switch t.driverName {
+	case "mysql":
+		cfg, err := mysql.ParseDSN(t.dsn)
+		if err != nil {
+			return t, "", err
+		}
+		current := cfg.DBName
+		cfg.DBName = database
+		t.dsn = cfg.FormatDSN()
+		return t, current, nil
+	case "sqlserver":
+		u, err := url.Parse(t.dsn)
+		if err != nil {
+			return t, "", err
+		}
+		query := u.Query()
+		current := query.Get("database")
+		query.Set("database", database)
+		u.RawQuery = query.Encode()
+		t.dsn = u.String()
+		return t, current, nil
+	default:
+		u, err := url.Parse(t.dsn)
+		if err != nil {
+			return t, "", err
+		}
+		current := strings.TrimPrefix(u.Path, "/")
+		u.Path = "/" + database
+		t.dsn = u.String()
+		return t, current, nil
+	}
+}
+
+// maintenanceDatabase is the database to connect to while the database of
+// the target is copied or replaced.
+func maintenanceDatabase(engine string) string {
+	switch engine {
+	case engineMsSql:
+		return "master"
+	case engineMySql, engineMariaDb:
+		return "information_schema"
+	default:
+		return "postgres"
+	}
+}
+
+// openMaintenance opens a connection pool to the maintenance database of the
+// server of a target and returns it with the name of the target database.
+func openMaintenance(ctx context.Context, t target) (*sql.DB, string, error) {
+	maintenance, database, err := withDatabase(t, maintenanceDatabase(t.engine))
+	if err != nil {
+		return nil, "", err
+	}
+	return openTarget(ctx, maintenance), database, nil
+}
+
+// takeSnapshot copies the freshly loaded database of a target with the
+// fastest mechanism of the engine: a template database on PostgreSQL, a
+// database snapshot on MSSQL and a copy of the tablespace files in the
+// container on MySQL and MariaDB. No connection to the database may be open.
+func takeSnapshot(ctx context.Context, t target) error {
+	db, database, err := openMaintenance(ctx, t)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	snapshot := database + snapshotSuffix
+
+	switch t.engine {
+	case engineMsSql:
+		var files []string
+		err := scanRows(ctx, db, fmt.Sprintf("select name, physical_name from sys.master_files where database_id = db_id('%s') and type = 0", database), func(rows *sql.Rows) error {
+			var name, path string
+			if err := rows.Scan(&name, &path); err != nil {
+				return err
+			}
+			files = append(files, fmt.Sprintf("(name = %s, filename = '%s%s.ss')", schema.QuoteIdent(t.engine, name), path, snapshotSuffix))
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		return harness.Exec(ctx, db,
+			fmt.Sprintf("if db_id('%s') is not null drop database %s", snapshot, schema.QuoteIdent(t.engine, snapshot)),
+			fmt.Sprintf("create database %s on %s as snapshot of %s", schema.QuoteIdent(t.engine, snapshot), strings.Join(files, ", "), schema.QuoteIdent(t.engine, database)),
+		)
+	case engineMySql, engineMariaDb:
+		return takeTablespaceSnapshot(ctx, db, t, database)
+	default:
+		return harness.Exec(ctx, db,
+			fmt.Sprintf("drop database if exists %s", schema.QuoteIdent(t.engine, snapshot)),
+			fmt.Sprintf("create database %s template %s", schema.QuoteIdent(t.engine, snapshot), schema.QuoteIdent(t.engine, database)),
+		)
+	}
+}
+
+// restoreSnapshot brings the database of a target back to its snapshot and
+// returns how long it took. MySQL and MariaDB restore only the given tables,
+// all of them when none are given, the other engines replace the whole
+// database. No connection to the database may be open.
+func restoreSnapshot(ctx context.Context, t target, tables []string) (time.Duration, error) {
+	db, database, err := openMaintenance(ctx, t)
+	if err != nil {
+		return 0, err
+	}
+	defer db.Close()
+	snapshot := database + snapshotSuffix
+
+	exists, err := snapshotExists(ctx, db, t, database)
+	if err != nil {
+		return 0, err
+	}
+	if !exists {
+		return 0, fmt.Errorf("there is no snapshot %s, run the snapshot command", snapshot)
+	}
+
+	start := time.Now()
+	switch t.engine {
+	case engineMsSql:
+		err = harness.Exec(ctx, db,
+			fmt.Sprintf("alter database %s set single_user with rollback immediate", schema.QuoteIdent(t.engine, database)),
+			fmt.Sprintf("restore database %s from database_snapshot = '%s'", schema.QuoteIdent(t.engine, database), snapshot),
+		)
+		// the database has to accept connections again even when the restore
+		// failed or was interrupted
+		if _, multiUserErr := db.ExecContext(context.Background(), fmt.Sprintf("alter database %s set multi_user", schema.QuoteIdent(t.engine, database))); err == nil {
+			err = multiUserErr
+		}
+	case engineMySql, engineMariaDb:
+		err = restoreTablespaceSnapshot(ctx, db, t, database, tables)
+	default:
+		err = harness.Exec(ctx, db,
+			fmt.Sprintf("drop database if exists %s with (force)", schema.QuoteIdent(t.engine, database)),
+			fmt.Sprintf("create database %s template %s", schema.QuoteIdent(t.engine, database), schema.QuoteIdent(t.engine, snapshot)),
+		)
+	}
+	return time.Since(start), err
+}
+
+// ensureSnapshot takes the snapshot of a target unless it exists. Until the
+// first destructive test the database is as setup loaded it.
+func ensureSnapshot(ctx context.Context, t target) error {
+	db, database, err := openMaintenance(ctx, t)
+	if err != nil {
+		return err
+	}
+	exists, err := snapshotExists(ctx, db, t, database)
+	db.Close()
+	if err != nil || exists {
+		return err
+	}
+	snapshot(ctx, t)
+	return nil
+}
+
+// dropSnapshot drops the snapshot of a target, if there is one. A MySQL or
+// MariaDB target without a container has none.
+func dropSnapshot(ctx context.Context, t target) error {
+	db, database, err := openMaintenance(ctx, t)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	snapshot := database + snapshotSuffix
+
+	switch t.engine {
+	case engineMsSql:
+		return harness.Exec(ctx, db, fmt.Sprintf("if db_id('%s') is not null drop database %s", snapshot, schema.QuoteIdent(t.engine, snapshot)))
+	case engineMySql, engineMariaDb:
+		if t.container == "" {
+			return nil
+		}
+		return execInContainer(ctx, t, "rm", "-rf", tablespaceSnapshotDir(database))
+	default:
+		return harness.Exec(ctx, db, fmt.Sprintf("drop database if exists %s", schema.QuoteIdent(t.engine, snapshot)))
+	}
+}
+
+// snapshotExists reports whether the snapshot of a database was taken.
+func snapshotExists(ctx context.Context, db *sql.DB, t target, database string) (bool, error) {
+	snapshot := database + snapshotSuffix
+	switch t.engine {
+	case engineMsSql:
+		exists, err := queryCount(ctx, db, fmt.Sprintf("select count(*) from sys.databases where name = '%s' and source_database_id is not null", snapshot))
+		return exists > 0, err
+	case engineMySql, engineMariaDb:
+		if err := execInContainer(ctx, t, "test", "-d", tablespaceSnapshotDir(database)); err != nil {
+			if t.container == "" {
+				return false, err
+			}
+			return false, nil
+		}
+		return true, nil
+	default:
+		exists, err := queryCount(ctx, db, fmt.Sprintf("select count(*) from pg_database where datname = '%s'", snapshot))
+		return exists > 0, err
+	}
+}
+
+// takeTablespaceSnapshot copies the tablespace files of every table of a
+// MySQL or MariaDB database while the tables are flushed for export, the
+// copy is a file copy and not a reload of the rows.
+func takeTablespaceSnapshot(ctx context.Context, db *sql.DB, t target, database string) error {
+	tables, err := baseTables(ctx, db, database)
+	if err != nil {
+		return err
+	}
+	if len(tables) == 0 {
+		return fmt.Errorf("there are no tables in %s", database)
+	}
+
+	// the tables stay flushed and locked only as long as this session holds the lock
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	dataDir, err := databaseDir(ctx, conn, database)
+	if err != nil {
+		return err
+	}
+
+	qualified := make([]string, len(tables))
+	for i, table := range tables {
+		qualified[i] = schema.QuoteIdent(t.engine, database) + "." + schema.QuoteIdent(t.engine, table)
+	}
+	if _, err := conn.ExecContext(ctx, "flush tables "+strings.Join(qualified, ", ")+" for export"); err != nil {
+		return err
+	}
+	copyErr := execInContainer(ctx, t, copyTablespacesCmd(dataDir, tablespaceSnapshotDir(database), tables, true)...)
+	_, unlockErr := conn.ExecContext(context.Background(), "unlock tables")
+	return errors.Join(copyErr, unlockErr)
+}
+
+// restoreTablespaceSnapshot replaces the tablespaces of the given tables, all
+// of them when none are given, with their copies from the snapshot.
+func restoreTablespaceSnapshot(ctx context.Context, db *sql.DB, t target, database string, tables []string) error {
+	var err error
+	if len(tables) == 0 {
+		if tables, err = baseTables(ctx, db, database); err != nil {
+			return err
+		}
+	}
+
+	// foreign_key_checks is a session setting, discard and import have to run on the same connection
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	dataDir, err := databaseDir(ctx, conn, database)
+	if err != nil {
+		return err
+	}
+
+	discard := []string{"set foreign_key_checks = 0"}
+	var imports []string
+	for _, table := range tables {
+		qualified := schema.QuoteIdent(t.engine, database) + "." + schema.QuoteIdent(t.engine, table)
+		discard = append(discard, fmt.Sprintf("alter table %s discard tablespace", qualified))
+		imports = append(imports, fmt.Sprintf("alter table %s import tablespace", qualified))
+	}
+	imports = append(imports, "set foreign_key_checks = 1")
+
+	if err := harness.Exec(ctx, conn, discard...); err != nil {
+		return err
+	}
+	if err := execInContainer(ctx, t, copyTablespacesCmd(tablespaceSnapshotDir(database), dataDir, tables, false)...); err != nil {
+		return err
+	}
+	return harness.Exec(ctx, conn, imports...)
+}
+
+func tablespaceSnapshotDir(database string) string {
+	return path.Join(tablespaceSnapshots, database)
+}
+
+// databaseDir is the directory of a database in the data directory of the server.
+func databaseDir(ctx context.Context, conn *sql.Conn, database string) (string, error) {
+	var dataDir string
+	if err := conn.QueryRowContext(ctx, "select @@datadir").Scan(&dataDir); err != nil {
+		return "", err
+	}
+	return path.Join(dataDir, database), nil
+}
+
+// copyTablespacesCmd copies the tablespace and export metadata files of
+// tables from one directory to another, fresh replaces the whole target
+// directory. The owner of the files is kept, the server has to open them.
+func copyTablespacesCmd(from string, to string, tables []string, fresh bool) []string {
+	var files []string
+	for _, table := range tables {
+		files = append(files, shellQuote(path.Join(from, table+".ibd")), shellQuote(path.Join(from, table+".cfg")))
+	}
+	script := fmt.Sprintf("cp -p %s %s/", strings.Join(files, " "), shellQuote(to))
+	if fresh {
+		script = fmt.Sprintf("rm -rf %[1]s && mkdir -p %[1]s && %[2]s", shellQuote(to), script)
+	}
+	return []string{"sh", "-c", script}
+}
+
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// execInContainer runs a command in the container of a target and fails
+// when it exits with a non zero code.
+func execInContainer(ctx context.Context, t target, cmd ...string) error {
+	if t.container == "" {
+		return fmt.Errorf("%s has no container, snapshots of %s copy files in it, set it in the config or use -containers", t.connectionName, t.engine)
+	}
+	cli, err := client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	if err != nil {
+		return err
+	}
+	defer cli.Close()
+
+	exec, err := cli.ContainerExecCreate(ctx, t.container, types.ExecConfig{Cmd: cmd, AttachStdout: true, AttachStderr: true})
+	if err != nil {
+		return err
+	}
+	attach, err := cli.ContainerExecAttach(ctx, exec.ID, types.ExecStartCheck{})
+	if err != nil {
+		return err
+	}
+	defer attach.Close()
+	output, err := io.ReadAll(attach.Reader)
+	if err != nil {
+		return err
+	}
+
+	state, err := cli.ContainerExecInspect(ctx, exec.ID)
+	if err != nil {
+		return err
+	}
+	if state.ExitCode != 0 {
+		return fmt.Errorf("%v in %s failed with exit code %d: %s", cmd, t.container, state.ExitCode, strings.TrimSpace(string(output)))
+	}
+	return nil
+}
+
+func baseTables(ctx context.Context, db *sql.DB, database string) ([]string, error) {
+	var tables []string
+	err := scanRows(ctx, db, fmt.Sprintf("select table_name from information_schema.tables where table_schema = '%s' and table_type = 'BASE TABLE'", database), func(rows *sql.Rows) error {
+		var table string
+		if err := rows.Scan(&table); err != nil {
+			return err
+		}
+		tables = append(tables, table)
+		return nil
+	})
+	return tables, err
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"regexp"
+	"strings"
+)
+
+// tableRef is a table referenced in the from clause of a query.
+type tableRef struct {
+	name  string // unquoted table name
+	alias string // alias, or the table name when the query does not use one
+	end   int    // offset right after the reference and its alias, where table hints go
+}
+
+var tableRefPattern = regexp.MustCompile("(?i)\\b(?:from|join)\\s+[`\"\\[]?(\\w+)[`\"\\]]?(?:\\s+(?:as\\s+)?(\\w+))?")
+
+// words that can follow a table reference and therefore are not an alias
+var sqlKeywords = map[string]struct{}{
+	"where": {}, "inner": {}, "left": {}, "right": {}, "full": {}, "outer": {}, "cross": {}, "join": {},
+	"on": {}, "group": {}, "order": {}, "having": {}, "limit": {}, "union": {}, "with": {}, "option": {},
+	"ignore": {}, "force": {}, "use": {}, "loop": {}, "hash": {}, "merge": {}, "natural": {}, "straight_join": {},
+}
+
+// tableRefs returns the table references of a query in order of appearance.
+// CTE references are returned as well, they are indistinguishable from
+// tables for a simple text scan.
+func tableRefs(query string) []tableRef {
+	var refs []tableRef
+	for _, m := range tableRefPattern.FindAllStringSubmatchIndex(query, -1) {
+		ref := tableRef{name: query[m[2]:m[3]], alias: query[m[2]:m[3]], end: m[3]}
+		if m[3] < len(query) && strings.ContainsRune("`\"]", rune(query[m[3]])) {
+			ref.end = m[3] + 1
+		}
+		if m[4] >= 0 {
+			if _, keyword := sqlKeywords[strings.ToLower(query[m[4]:m[5]])]; !keyword {
+				ref.alias = query[m[4]:m[5]]
+				ref.end = m[5]
+			}
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+var selectKeyword = regexp.MustCompile(`(?i)\bselect\b`)
+
+// withOptimizerHint adds a MySQL optimizer hint comment to every query block.
+func withOptimizerHint(query string, hint string) string {
+	return selectKeyword.ReplaceAllString(query, "${0} /*+ "+hint+" */")
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"testing"
+)
+
+func TestTableRefs(t *testing.T) {
+	query := "select min(order_id) from (select o.id as order_id from [order] as o inner join order_detail od on od.order_id = o.id where o.id > 0) as tmp;"
+	refs := tableRefs(query)
+
+	if len(refs) != 2 {
+		t.Fatalf("expected 2 table references, got %v", refs)
+	}
+	if refs[0].name != "order" || refs[0].alias != "o" || query[:refs[0].end] != "select min(order_id) from (select o.id as order_id from [order] as o" {
+		t.Errorf("unexpected first reference %+v", refs[0])
+	}
+	if refs[1].name != "order_detail" || refs[1].alias != "od" {
+		t.Errorf("unexpected second reference %+v", refs[1])
+	}
+
+	refs = tableRefs("select count(*) from client where id = 1;")
+	if len(refs) != 1 || refs[0].alias != "client" || refs[0].end != len("select count(*) from client") {
+		t.Errorf("unexpected reference without alias %+v", refs)
+	}
+}
+
+func TestWithOptimizerHint(t *testing.T) {
+	actual := withOptimizerHint("select a from (select a from t) as x", "NO_BNL()")
+	expected := "select /*+ NO_BNL() */ a from (select /*+ NO_BNL() */ a from t) as x"
+	if actual != expected {
+		t.Errorf("withOptimizerHint() = %q, expected %q", actual, expected)
+	}
+}
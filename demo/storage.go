@@ -0,0 +1,132 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+var storageFlag = flag.String("storage", "", "comma separated storage backends of the data directories: overlay, bind, tmpfs, needs -containers")
+var dataDirFlag = flag.String("data-dir", "", "host directory the bind storage creates the data directories in, the temp directory when empty")
+
+// storage backends of the data directory of a container
+const (
+	// the writable layer of the container
+	storageOverlay = "overlay"
+	// a directory of the host mounted into the container
+	storageBind = "bind"
+	// memory, it separates the efficiency of an engine from the speed of the disk
+	storageTmpfs = "tmpfs"
+)
+
+// the data directory of every engine in its image
+var dataDirectories = map[string]string{
+	engineMariaDb:  "/var/lib/mysql",
+	engineMySql:    "/var/lib/mysql",
+	enginePostgres: "/var/lib/postgresql/data",
+	engineMsSql:    "/var/opt/mssql",
+}
+
+// the data directory of every engine on the writable layer of its container.
+// The images declare a VOLUME for their default data directory, docker puts
+// it on an anonymous volume and not on the overlay filesystem. MSSQL declares
+// none.
+var overlayDirectories = map[string]string{
+	engineMariaDb:  "/mysql-data",
+	engineMySql:    "/mysql-data",
+	enginePostgres: "/pgdata",
+}
+
+// expandStorage returns a target per target and storage backend, named after
+// both, e.g. "pg-17.5 [tmpfs]". MSSQL does not run on tmpfs, it needs
+// O_DIRECT.
+func expandStorage(targets []target, names string) ([]target, error) {
+	if names == "" {
+		return targets, nil
+	}
+
+	var expanded []target
+	for _, name := range strings.Split(names, ",") {
+		name = strings.TrimSpace(name)
+		if name != storageOverlay && name != storageBind && name != storageTmpfs {
+			return nil, fmt.Errorf("unknown storage %q", name)
+		}
+		for _, t := range targets {
+			if name == storageTmpfs && t.engine == engineMsSql {
+				log.Printf("%s: skipping storage %s, MSSQL does not support it", t.connectionName, name)
+				continue
+			}
+			st := t
+			st.connectionName = profileName(t.connectionName, name)
+			st.storage = name
+			st.labels = map[string]string{"storage": name}
+			for k, v := range t.labels {
+				st.labels[k] = v
+			}
+			if st.queries == "" {
+				st.queries = t.connectionName
+			}
+			expanded = append(expanded, st)
+		}
+	}
+	return expanded, nil
+}
+
+var unsafePathChars = regexp.MustCompile(`[^\w.-]+`)
+
+// storageRequest puts the data directory of the container of a target on its
+// storage backend, without one it stays where the image puts it. PostgreSQL
+// gets a subdirectory of a mount, initdb refuses a mount point that is not
+// empty.
+func storageRequest(req *testcontainers.ContainerRequest, t target, dataDir string) error {
+	dir, ok := dataDirectories[t.engine]
+	if !ok {
+		return fmt.Errorf("unknown engine %s", t.engine)
+	}
+
+	switch t.storage {
+	case "":
+		return nil
+	case storageOverlay:
+		// the entrypoints create the data directory and give it to the server user
+		switch overlay := overlayDirectories[t.engine]; t.engine {
+		case enginePostgres:
+			req.Env["PGDATA"] = overlay
+		case engineMySql, engineMariaDb:
+			req.Cmd = append(req.Cmd, "--datadir="+overlay)
+		}
+		return nil
+	case storageBind:
+		if dataDir == "" {
+			dataDir = os.TempDir()
+		}
+		hostDir, err := os.MkdirTemp(dataDir, unsafePathChars.ReplaceAllString(t.connectionName, "_")+"-")
+		if err != nil {
+			return err
+		}
+		// the engines do not run as root in their containers
+		if err := os.Chmod(hostDir, 0o777); err != nil {
+			return err
+		}
+		log.Printf("%s: data directory %s", t.connectionName, hostDir)
+		req.Mounts = append(req.Mounts, testcontainers.BindMount(hostDir, testcontainers.ContainerMountTarget(dir)))
+	case storageTmpfs:
+		if req.Tmpfs == nil {
+			req.Tmpfs = make(map[string]string)
+		}
+		req.Tmpfs[dir] = "rw,mode=1777"
+	default:
+		return fmt.Errorf("unknown storage %q", t.storage)
+	}
+
+	if t.engine == enginePostgres {
+		req.Env["PGDATA"] = path.Join(dir, "pgdata")
+	}
+	return nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/testcontainers/testcontainers-go"
+)
+
+func TestExpandStorage(t *testing.T) {
+	targets := []target{
+		{connectionName: PostgreSql17, engine: enginePostgres},
+		{connectionName: MsSql22, engine: engineMsSql},
+	}
+
+	expanded, err := expandStorage(targets, "bind,tmpfs")
+	if err != nil {
+		t.Fatalf("expandStorage() failed: %v", err)
+	}
+	expected := []string{"pg-17.5 [bind]", "mssql-22-CU19 [bind]", "pg-17.5 [tmpfs]"}
+	if len(expanded) != len(expected) {
+		t.Fatalf("expandStorage() = %v, expected %v", expanded, expected)
+	}
+	for i, name := range expected {
+		if expanded[i].connectionName != name || expanded[i].labels["storage"] != expanded[i].storage {
+			t.Errorf("expandStorage()[%d] = %+v, expected %s", i, expanded[i], name)
+		}
+	}
+
+	if _, err := expandStorage(targets, "nfs"); err == nil {
+		t.Errorf("expandStorage() of an unknown storage did not fail")
+	}
+}
+
+func TestStorageRequest(t *testing.T) {
+	req := testcontainers.ContainerRequest{Env: map[string]string{}}
+	if err := storageRequest(&req, target{connectionName: PostgreSql17, engine: enginePostgres, storage: storageTmpfs}, ""); err != nil {
+		t.Fatalf("storageRequest(tmpfs) failed: %v", err)
+	}
+	if _, ok := req.Tmpfs["/var/lib/postgresql/data"]; !ok || req.Env["PGDATA"] != "/var/lib/postgresql/data/pgdata" {
+		t.Errorf("storageRequest(tmpfs) = %v, %v", req.Tmpfs, req.Env)
+	}
+
+	req = testcontainers.ContainerRequest{Env: map[string]string{}}
+	dataDir := t.TempDir()
+	if err := storageRequest(&req, target{connectionName: MySql9, engine: engineMySql, storage: storageBind}, dataDir); err != nil {
+		t.Fatalf("storageRequest(bind) failed: %v", err)
+	}
+	if len(req.Mounts) != 1 || req.Mounts[0].Target != "/var/lib/mysql" {
+		t.Fatalf("storageRequest(bind) = %+v", req.Mounts)
+	}
+	if entries, _ := os.ReadDir(dataDir); len(entries) != 1 {
+		t.Errorf("storageRequest(bind) created %d directories, expected 1", len(entries))
+	}
+
+	req = testcontainers.ContainerRequest{Env: map[string]string{}}
+	if err := storageRequest(&req, target{engine: engineMySql}, ""); err != nil || len(req.Mounts) != 0 || req.Tmpfs != nil || req.Cmd != nil {
+		t.Errorf("storageRequest(image default) = %+v, %v", req, err)
+	}
+}
+
+func TestOverlayStorageRequest(t *testing.T) {
+	data := []struct {
+		engine string
+		cmd    []string
+		pgdata string
+	}{
+		{enginePostgres, nil, "/pgdata"},
+		{engineMySql, []string{"--innodb_buffer_pool_size=268435456", "--datadir=/mysql-data"}, ""},
+		{engineMariaDb, []string{"--innodb_buffer_pool_size=268435456", "--datadir=/mysql-data"}, ""},
+		{engineMsSql, nil, ""},
+	}
+
+	for _, d := range data {
+		req := testcontainers.ContainerRequest{Env: map[string]string{}}
+		if d.engine == engineMySql || d.engine == engineMariaDb {
+			req.Cmd = []string{"--innodb_buffer_pool_size=268435456"}
+		}
+		if err := storageRequest(&req, target{engine: d.engine, storage: storageOverlay}, ""); err != nil {
+			t.Fatalf("storageRequest(%s, overlay) failed: %v", d.engine, err)
+		}
+		// the data directory is not under the VOLUME of the image
+		if !reflect.DeepEqual(req.Cmd, d.cmd) || req.Env["PGDATA"] != d.pgdata || len(req.Mounts) != 0 || req.Tmpfs != nil {
+			t.Errorf("storageRequest(%s, overlay) = %+v", d.engine, req)
+		}
+	}
+}
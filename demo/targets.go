@@ -0,0 +1,160 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+)
+
+var targetsFile = flag.String("config", "demo/targets.json", "file with the targets to run against")
+var targetsFlag = flag.String("targets", "", "comma separated targets to run against, by name or label=value, all when empty")
+var engineFlag = flag.String("engine", "", "comma separated engines to run against, all when empty")
+
+// targetConfig is a target in the config file. The DSN is a template,
+// ${VAR} is replaced with the environment variable and ${VAR:-default} falls
+// back to the default when it is not set. Passwords have no default, they
+// come from the environment only.
+type targetConfig struct {
+	Name   string `json:"name"`
+	Engine string `json:"engine"`
+	Driver string `json:"driver"`
+	Dsn    string `json:"dsn"`
+	// Container is the name of the container of the server, the exclusive
+	// mode pauses it while other targets are measured
+	Container string            `json:"container"`
+	Image     string            `json:"image"`
+	Labels    map[string]string `json:"labels"`
+	// Queries names the target whose queries to run, when the tests have
+	// none for this one, e.g. a new minor version of the same engine
+	Queries string `json:"queries"`
+}
+
+// loadTargets reads the targets from a config file. The DSNs stay templates
+// until resolveDsns, only the selected targets need their variables.
+func loadTargets(path string) ([]target, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var configs []targetConfig
+	if err := json.Unmarshal(data, &configs); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+
+	seen := make(map[string]struct{})
+	targets := make([]target, 0, len(configs))
+	for _, c := range configs {
+		if c.Name == "" || c.Engine == "" || c.Driver == "" || c.Dsn == "" {
+			return nil, fmt.Errorf("%s: a target needs a name, engine, driver and dsn: %+v", path, c)
+		}
+		if _, ok := seen[c.Name]; ok {
+			return nil, fmt.Errorf("%s: duplicate target %s", path, c.Name)
+		}
+		seen[c.Name] = struct{}{}
+
+		targets = append(targets, target{
+			connectionName: c.Name,
+			engine:         c.Engine,
+			driverName:     c.Driver,
+			dsn:            c.Dsn,
+			container:      c.Container,
+			image:          c.Image,
+			labels:         c.Labels,
+			queries:        c.Queries,
+		})
+	}
+	return targets, nil
+}
+
+// resolveDsns replaces the variables in the DSNs of the targets with the environment.
+func resolveDsns(targets []target) error {
+	for i, t := range targets {
+		dsn, err := expandEnv(t.dsn)
+		if err != nil {
+			return fmt.Errorf("%s: %w", t.connectionName, err)
+		}
+		targets[i].dsn = dsn
+	}
+	return nil
+}
+
+// expandEnv replaces ${VAR} and ${VAR:-default} with the environment. A
+// variable without a default has to be set.
+func expandEnv(s string) (string, error) {
+	var missing []string
+	expanded := os.Expand(s, func(name string) string {
+		name, fallback, hasDefault := strings.Cut(name, ":-")
+		if value, ok := os.LookupEnv(name); ok && value != "" {
+			return value
+		}
+		if !hasDefault {
+			missing = append(missing, name)
+		}
+		return fallback
+	})
+	if len(missing) > 0 {
+		return "", fmt.Errorf("environment variables not set: %s, the DSN takes its passwords from the environment", strings.Join(missing, ", "))
+	}
+	return expanded, nil
+}
+
+// selectTargets keeps the targets matching the -targets and -engine flags.
+// A target matches -targets by its name or by one of its labels as
+// label=value.
+func selectTargets(targets []target, names string, engines string) ([]target, error) {
+	var selected []target
+	for _, t := range targets {
+		if matchesAny(engines, func(e string) bool { return e == t.engine }) &&
+			matchesAny(names, func(n string) bool { return n == t.connectionName || t.hasLabel(n) }) {
+			selected = append(selected, t)
+		}
+	}
+	if len(selected) == 0 {
+		return nil, fmt.Errorf("no targets match -targets=%q -engine=%q", names, engines)
+	}
+	return selected, nil
+}
+
+// matchesAny reports whether one of the comma separated values matches, an
+// empty list matches everything.
+func matchesAny(values string, match func(string) bool) bool {
+	if values == "" {
+		return true
+	}
+	for _, v := range strings.Split(values, ",") {
+		if match(strings.TrimSpace(v)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (t target) hasLabel(selector string) bool {
+	key, value, ok := strings.Cut(selector, "=")
+	if !ok {
+		return false
+	}
+	v, ok := t.labels[key]
+	return ok && v == value
+}
+
+// queriesOf returns the queries of a test for a target: the ones for its
+// name, the target named by its queries setting, or its engine.
+func (test testData) queriesOf(t target) (map[string]string, bool) {
+	for _, key := range t.queryKeys() {
+		if queries, ok := test.queries[key]; ok {
+			return queries, true
+		}
+	}
+	return nil, false
+}
+
+func (t target) queryKeys() []string {
+	keys := []string{t.connectionName}
+	if t.queries != "" {
+		keys = append(keys, t.queries)
+	}
+	return append(keys, t.engine)
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/solontsev/rdbms-performance-comparison/harness"
+)
+
+// variant is one way of executing a query: the query text plus the session
+// statements that have to run before and after it on the same connection.
+type variant struct {
+	name string
+	// queryName is the name of the query the variant is generated from
+	queryName string
+	query     string
+	setup     []string
+	teardown  []string
+	// cleanup runs after every execution, e.g. to drop temporary tables of a script
+	cleanup []string
+	// verify inspects the session before the measurement and returns a note
+	// for the result table, e.g. when the engine ignored a forced plan choice
+	verify func(ctx context.Context, conn *sql.Conn, query string) (string, error)
+	// skip is the reason the variant cannot run on the engine
+	skip string
+	// scale is the scale factor of the tables the variant runs on
+	scale float64
+}
+
+// expandVariants returns every variant of a query the test and the command
+// line ask for: forced access paths and join algorithms first, then each of
+// them at every degree of parallelism.
+func expandVariants(t target, test testData, queryName string, name string, query string, dops []int) []variant {
+	variants := []variant{{name: name, queryName: queryName, query: query, cleanup: tempTableCleanup(t.engine, query)}}
+	for _, h := range test.hints {
+		variants = append(variants, hintVariant(t, variants[0], h))
+	}
+	for _, algorithm := range test.joins {
+		variants = append(variants, joinVariant(t.engine, variants[0], algorithm))
+	}
+
+	if len(dops) == 0 {
+		return variants
+	}
+	expanded := make([]variant, 0, len(variants)*len(dops))
+	for _, v := range variants {
+		if v.skip != "" {
+			expanded = append(expanded, v)
+			continue
+		}
+		expanded = append(expanded, dopVariants(t.engine, v, dops)...)
+	}
+	return expanded
+}
+
+// cell is a single value of the result table.
+type cell struct {
+	duration time.Duration
+	note     string
+}
+
+func (c cell) String() string {
+	switch {
+	case c.duration == 0:
+		return c.note
+	case c.note == "":
+		return c.duration.String()
+	default:
+		return fmt.Sprintf("%s (%s)", c.duration, c.note)
+	}
+}
+
+// withNote adds a note to the cell, keeping the notes it already has.
+func (c cell) withNote(note string) cell {
+	switch {
+	case note == "":
+	case c.note == "":
+		c.note = note
+	default:
+		c.note += "; " + note
+	}
+	return c
+}
+
+// execVariant runs the variant on a pinned connection, so its session settings
+// are visible to the query and do not leak into other pooled connections.
+// Setup, teardown, cleanup, session resets and verification are not part of
+// the measured time.
+func execVariant(ctx context.Context, t target, test testData, db *sql.DB, v variant, execs int) (c cell) {
+	if v.skip != "" {
+		return cell{note: v.skip}
+	}
+
+	s := openSession(ctx, db, t.engine, test.session, v.setup)
+	defer s.Close()
+
+	h := test.hooksOf(v)
+	if failure := h.runSetup(ctx, s.conn, t.engine, v.scale); failure != "" {
+		return cell{note: failure}
+	}
+	defer func() {
+		c = c.withNote(h.runTeardown(ctx, s.conn, t.engine, v.scale))
+	}()
+
+	var note string
+	var err error
+	if v.verify != nil {
+		if note, err = v.verify(ctx, s.conn, v.query); err != nil {
+			fatalf("unable to verify %s: %v", v.name, err)
+		}
+	}
+	if test.dml != nil {
+		c := execDml(ctx, s, test, v, execs)
+		if err := harness.Exec(ctx, s.conn, v.teardown...); err != nil {
+			fatalf("unable to execute %v", err)
+		}
+		return c.withNote(note)
+	}
+
+	after := func() {
+		if err := harness.Exec(ctx, s.conn, v.cleanup...); err != nil {
+			fatalf("unable to execute %v", err)
+		}
+		s.prepare(ctx)
+	}
+	duration, err := ExecQuery(ctx, s, v.query, execs, after)
+	if err != nil {
+		fatalf("unable to execute %s: %v", v.name, err)
+	}
+	if err := harness.Exec(ctx, s.conn, v.teardown...); err != nil {
+		fatalf("unable to execute %v", err)
+	}
+
+	return cell{duration: duration.Round(time.Millisecond), note: note}
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/jedib0t/go-pretty/v6/table"
+)
+
+var versionsFlag = flag.String("versions", "", "image tags to run every engine with, e.g. \"postgres=15.7,16.3,17.5;mysql=8.4.5,9.3.0\", needs -containers")
+
+// versionMatrix is the list of image tags an engine runs with, in the order
+// they are compared.
+type versionMatrix struct {
+	engine string
+	tags   []string
+}
+
+// parseVersions parses the -versions flag.
+func parseVersions(value string) ([]versionMatrix, error) {
+	if value == "" {
+		return nil, nil
+	}
+
+	var matrix []versionMatrix
+	seen := make(map[string]struct{})
+	for _, entry := range strings.Split(value, ";") {
+		engine, tags, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || engine == "" || tags == "" {
+			return nil, fmt.Errorf("invalid versions %q, expected engine=tag,tag", entry)
+		}
+		if _, ok := seen[engine]; ok {
+			return nil, fmt.Errorf("duplicate versions of %s", engine)
+		}
+		seen[engine] = struct{}{}
+
+		m := versionMatrix{engine: engine}
+		for _, tag := range strings.Split(tags, ",") {
+			if tag = strings.TrimSpace(tag); tag != "" {
+				m.tags = append(m.tags, tag)
+			}
+		}
+		matrix = append(matrix, m)
+	}
+	return matrix, nil
+}
+
+// imageWithTag replaces the tag of an image, a port of the registry is not a tag.
+func imageWithTag(image string, tag string) string {
+	if i := strings.LastIndex(image, ":"); i > strings.LastIndex(image, "/") {
+		image = image[:i]
+	}
+	return image + ":" + tag
+}
+
+// expandVersions replaces the targets of every engine in the matrix with a
+// target per tag. They are copies of the first target of the engine, named
+// after its prefix and the tag, e.g. "pg-15.7", and run its queries.
+func expandVersions(targets []target, matrix []versionMatrix) ([]target, error) {
+	for _, m := range matrix {
+		var template *target
+		var others []target
+		for i, t := range targets {
+			if t.engine != m.engine {
+				others = append(others, t)
+			} else if template == nil {
+				template = &targets[i]
+			}
+		}
+		if template == nil {
+			return nil, fmt.Errorf("there is no %s target to run the versions with", m.engine)
+		}
+		if template.image == "" {
+			return nil, fmt.Errorf("%s has no image to change the tag of", template.connectionName)
+		}
+
+		for _, tag := range m.tags {
+			v := *template
+			v.connectionName = versionName(*template, tag)
+			v.image = imageWithTag(template.image, tag)
+			v.labels = map[string]string{"version": tag}
+			for k, value := range template.labels {
+				if k != "version" {
+					v.labels[k] = value
+				}
+			}
+			if v.queries == "" {
+				v.queries = template.connectionName
+			}
+			others = append(others, v)
+		}
+		targets = others
+	}
+	return targets, nil
+}
+
+func versionName(template target, tag string) string {
+	prefix, _, ok := strings.Cut(template.connectionName, "-")
+	if !ok {
+		prefix = template.engine
+	}
+	return prefix + "-" + tag
+}
+
+// versionGroup is the targets of an engine with the same configuration, the
+// profile and the storage, in the order of the matrix.
+type versionGroup struct {
+	config string
+	names  []string
+}
+
+// versionGroups returns the targets of an engine grouped by their
+// configuration, a version is only compared with the versions of its group.
+func versionGroups(targets []target, engine string) []versionGroup {
+	var groups []versionGroup
+	index := make(map[string]int)
+	for _, t := range targets {
+		if t.engine != engine {
+			continue
+		}
+		var config []string
+		for _, value := range []string{t.profile, t.storage} {
+			if value != "" {
+				config = append(config, value)
+			}
+		}
+		key := strings.Join(config, ", ")
+		i, ok := index[key]
+		if !ok {
+			i = len(groups)
+			index[key] = i
+			groups = append(groups, versionGroup{config: key})
+		}
+		groups[i].names = append(groups[i].names, t.connectionName)
+	}
+	return groups
+}
+
+// printVersionChart prints a table per engine of the matrix and configuration
+// with the versions side by side, every time notes the change against the
+// previous version, so it shows which release improved or regressed a query.
+func printVersionChart(result map[string]map[string]cell, targets []target, matrix []versionMatrix) {
+	for _, m := range matrix {
+		for _, g := range versionGroups(targets, m.engine) {
+			printVersionGroup(result, m.engine, g)
+		}
+	}
+}
+
+// printVersionGroup prints the table of the versions of a group.
+func printVersionGroup(result map[string]map[string]cell, engine string, g versionGroup) {
+	title := engine
+	if g.config != "" {
+		title = fmt.Sprintf("%s [%s]", engine, g.config)
+	}
+	t := table.NewWriter()
+	t.SetOutputMirror(os.Stdout)
+	header := table.Row{""}
+	for _, name := range g.names {
+		header = append(header, strings.Replace(name, "-", "\n", 1))
+	}
+	t.AppendHeader(header)
+	t.SetTitle(title)
+
+	var rows []string
+	seen := make(map[string]struct{})
+	for _, name := range g.names {
+		for queryName := range result[name] {
+			if _, ok := seen[queryName]; !ok {
+				seen[queryName] = struct{}{}
+				rows = append(rows, queryName)
+			}
+		}
+	}
+	sort.Strings(rows)
+
+	for _, queryName := range rows {
+		row := table.Row{queryName}
+		var previous cell
+		for _, name := range g.names {
+			c := result[name][queryName]
+			row = append(row, versionCell(c, previous))
+			previous = c
+		}
+		t.AppendRow(row)
+	}
+	t.Render()
+}
+
+// versionCell is the time of a version with the change against the previous one.
+func versionCell(c cell, previous cell) string {
+	if c.duration == 0 {
+		return c.String()
+	}
+	if previous.duration == 0 {
+		return c.duration.String()
+	}
+	change := (float64(c.duration)/float64(previous.duration) - 1) * 100
+	return fmt.Sprintf("%s (%+.0f%%)", c.duration, change)
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestParseVersions(t *testing.T) {
+	matrix, err := parseVersions("postgres=15.7, 16.3,17.5; mysql=8.4.5")
+	if err != nil {
+		t.Fatalf("parseVersions() failed: %v", err)
+	}
+	if len(matrix) != 2 || matrix[0].engine != enginePostgres || len(matrix[0].tags) != 3 || matrix[0].tags[1] != "16.3" || matrix[1].engine != engineMySql {
+		t.Errorf("parseVersions() = %v", matrix)
+	}
+
+	for _, value := range []string{"postgres", "postgres=", "postgres=15.7;postgres=16.3"} {
+		if _, err := parseVersions(value); err == nil {
+			t.Errorf("parseVersions(%q) did not fail", value)
+		}
+	}
+}
+
+func TestImageWithTag(t *testing.T) {
+	data := []struct {
+		image    string
+		expected string
+	}{
+		{"postgres:17.5", "postgres:15.7"},
+		{"postgres", "postgres:15.7"},
+		{"localhost:5000/postgres", "localhost:5000/postgres:15.7"},
+		{"localhost:5000/postgres:17.5", "localhost:5000/postgres:15.7"},
+	}
+
+	for _, d := range data {
+		if actual := imageWithTag(d.image, "15.7"); actual != d.expected {
+			t.Errorf("imageWithTag(%q) = %q, expected %q", d.image, actual, d.expected)
+		}
+	}
+}
+
+func TestExpandVersions(t *testing.T) {
+	targets := []target{
+		{connectionName: PostgreSql17, engine: enginePostgres, image: "postgres:17.5"},
+		{connectionName: PostgreSql18, engine: enginePostgres, image: "postgres:18beta1"},
+		{connectionName: MySql9, engine: engineMySql, image: "mysql:9.3.0"},
+	}
+
+	expanded, err := expandVersions(targets, []versionMatrix{{engine: enginePostgres, tags: []string{"15.7", "16.3"}}})
+	if err != nil {
+		t.Fatalf("expandVersions() failed: %v", err)
+	}
+	expected := []target{
+		{connectionName: MySql9, image: "mysql:9.3.0"},
+		{connectionName: "pg-15.7", image: "postgres:15.7", queries: PostgreSql17},
+		{connectionName: "pg-16.3", image: "postgres:16.3", queries: PostgreSql17},
+	}
+	if len(expanded) != len(expected) {
+		t.Fatalf("expandVersions() = %v, expected %v", expanded, expected)
+	}
+	for i, e := range expected {
+		if a := expanded[i]; a.connectionName != e.connectionName || a.image != e.image || a.queries != e.queries {
+			t.Errorf("expandVersions()[%d] = %s %s %s, expected %s %s %s", i, a.connectionName, a.image, a.queries, e.connectionName, e.image, e.queries)
+		}
+	}
+
+	if _, err := expandVersions(targets, []versionMatrix{{engine: engineMsSql, tags: []string{"2022-CU19-ubuntu-22.04"}}}); err == nil {
+		t.Errorf("expandVersions() of an engine without a target did not fail")
+	}
+}
+
+func TestVersionCell(t *testing.T) {
+	data := []struct {
+		c        cell
+		previous cell
+		expected string
+	}{
+		{cell{duration: time.Second}, cell{}, "1s"},
+		{cell{duration: 1500 * time.Millisecond}, cell{duration: time.Second}, "1.5s (+50%)"},
+		{cell{duration: 500 * time.Millisecond}, cell{duration: time.Second}, "500ms (-50%)"},
+		{cell{note: "table client is missing"}, cell{duration: time.Second}, "table client is missing"},
+	}
+
+	for _, d := range data {
+		if actual := versionCell(d.c, d.previous); actual != d.expected {
+			t.Errorf("versionCell(%v, %v) = %q, expected %q", d.c, d.previous, actual, d.expected)
+		}
+	}
+}
+
+func TestVersionGroups(t *testing.T) {
+	// the profiles are expanded after the versions, profile first
+	targets := []target{
+		{connectionName: "pg-15.7 [default]", engine: enginePostgres, profile: "default"},
+		{connectionName: "pg-16.3 [default]", engine: enginePostgres, profile: "default"},
+		{connectionName: "mysql-8.4.5", engine: engineMySql},
+		{connectionName: "pg-15.7 [non-durable]", engine: enginePostgres, profile: "non-durable"},
+		{connectionName: "pg-16.3 [non-durable] [tmpfs]", engine: enginePostgres, profile: "non-durable", storage: storageTmpfs},
+	}
+
+	groups := versionGroups(targets, enginePostgres)
+	expected := []versionGroup{
+		{config: "default", names: []string{"pg-15.7 [default]", "pg-16.3 [default]"}},
+		{config: "non-durable", names: []string{"pg-15.7 [non-durable]"}},
+		{config: "non-durable, tmpfs", names: []string{"pg-16.3 [non-durable] [tmpfs]"}},
+	}
+	if !reflect.DeepEqual(groups, expected) {
+		t.Errorf("versionGroups() = %+v, expected %+v", groups, expected)
+	}
+}
@@ -0,0 +1,120 @@
+package harness
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-connections/nat"
+	"github.com/testcontainers/testcontainers-go"
+	"github.com/testcontainers/testcontainers-go/wait"
+
+	"github.com/solontsev/rdbms-performance-comparison/config"
+)
+
+// Container is a started database server.
+type Container struct {
+	testcontainers.Container
+	// ServerHost and ServerPort are the address of the server on the host
+	ServerHost string
+	ServerPort nat.Port
+}
+
+// StartContainer starts the container of a request with the resource limits
+// and waits until the server on port accepts connections of the driver. url
+// builds the DSN the wait strategy connects with.
+func StartContainer(ctx context.Context, req testcontainers.ContainerRequest, resources Resources, driver string, port nat.Port, url func(host string, port nat.Port) string) (*Container, error) {
+	if !hasPort(req.ExposedPorts, port) {
+		req.ExposedPorts = append(req.ExposedPorts, string(port))
+	}
+	if resources.Shm > 0 {
+		req.ShmSize = resources.Shm
+	}
+	modify := req.HostConfigModifier
+	req.HostConfigModifier = func(hc *container.HostConfig) {
+		if modify != nil {
+			modify(hc)
+		}
+		resources.Apply(hc)
+	}
+	req.WaitingFor = wait.ForSQL(port, driver, url).WithStartupTimeout(config.ContainerStartupTimeout)
+
+	started, err := testcontainers.GenericContainer(ctx, testcontainers.GenericContainerRequest{
+		ContainerRequest: req,
+		Started:          true,
+	})
+	if err != nil {
+		if started != nil {
+			started.Terminate(context.Background())
+		}
+		return nil, err
+	}
+
+	c := &Container{Container: started}
+	if c.ServerHost, err = started.Host(ctx); err != nil {
+		return c, err
+	}
+	if c.ServerPort, err = started.MappedPort(ctx, port); err != nil {
+		return c, err
+	}
+	return c, nil
+}
+
+func hasPort(ports []string, port nat.Port) bool {
+	for _, p := range ports {
+		if p == string(port) {
+			return true
+		}
+	}
+	return false
+}
+
+// Run runs a command in the container and fails when it exits with a non
+// zero code. The output is logged.
+func (c *Container) Run(ctx context.Context, cmd ...string) error {
+	code, reader, err := c.Exec(ctx, cmd)
+	if err != nil {
+		return err
+	}
+	output := new(bytes.Buffer)
+	output.ReadFrom(reader)
+	log.Printf("%v result = %d, output:\n%s\n", cmd, code, output)
+	if code != 0 {
+		return fmt.Errorf("%v failed with exit code %d", cmd, code)
+	}
+	return nil
+}
+
+// InitScripts returns the *init*.sql files of a directory, copied to /tmp of
+// the container. They are not in /docker-entrypoint-initdb.d: every script
+// recreates its table and runs right before its query.
+func InitScripts(dir string) ([]testcontainers.ContainerFile, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	initSql := regexp.MustCompile(`.*init.*\.sql`)
+	var files []testcontainers.ContainerFile
+	for _, entry := range entries {
+		if entry.IsDir() || !initSql.MatchString(entry.Name()) {
+			continue
+		}
+		files = append(files, testcontainers.ContainerFile{
+			HostFilePath:      filepath.Join(dir, entry.Name()),
+			ContainerFilePath: ScriptPath(entry.Name()),
+			FileMode:          0o644,
+		})
+	}
+	return files, nil
+}
+
+// ScriptPath is the path of an init script in the container.
+func ScriptPath(script string) string {
+	return "/tmp/" + script
+}
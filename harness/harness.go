@@ -0,0 +1,272 @@
+// Package harness runs benchmark queries the same way for the demo runner,
+// the cmd tools and the per-engine tests: the same pool settings, timeouts,
+// warm-up and measurement.
+package harness
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/solontsev/rdbms-performance-comparison/config"
+	"github.com/solontsev/rdbms-performance-comparison/schema"
+)
+
+// Target is a database a suite runs against.
+type Target struct {
+	Name   string
+	Driver string
+	DSN    string
+}
+
+// Open opens the connection pool of the target and checks that the database
+// is reachable.
+func (t Target) Open(ctx context.Context) (*sql.DB, error) {
+	db, err := sql.Open(t.Driver, t.DSN)
+	if err != nil {
+		return nil, fmt.Errorf("unable to open %s: %w", t.Name, err)
+	}
+
+	db.SetConnMaxLifetime(0)
+	db.SetMaxIdleConns(config.MaxConnections)
+	db.SetMaxOpenConns(config.MaxConnections)
+
+	if err := Ping(ctx, db); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("unable to connect to %s: %w", t.Name, err)
+	}
+	return db, nil
+}
+
+// Ping checks that the database is reachable.
+func Ping(ctx context.Context, db *sql.DB) error {
+	ctx, cancel := context.WithTimeout(ctx, config.PingTimeout)
+	defer cancel()
+
+	return db.PingContext(ctx)
+}
+
+// Execer is implemented by *sql.DB, *sql.Conn and *sql.Tx.
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+// Exec runs statements one after another and stops at the first error.
+func Exec(ctx context.Context, db Execer, statements ...string) error {
+	for _, statement := range statements {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			return fmt.Errorf("%s: %w", statement, err)
+		}
+	}
+	return nil
+}
+
+// CreateTable drops and creates a table from its engine-neutral description
+// and logs the compromises of the engine. load, when set, fills the table
+// before the secondary indexes are created.
+func CreateTable(ctx context.Context, db *sql.DB, engine string, t schema.Table, load func() error) error {
+	ddl := schema.Generate(engine, t)
+	for _, c := range ddl.Compromises {
+		log.Printf("compromise: %s", c)
+	}
+	if err := Exec(ctx, db, ddl.Create...); err != nil {
+		return err
+	}
+	if load != nil {
+		if err := load(); err != nil {
+			return err
+		}
+	}
+	return Exec(ctx, db, ddl.Finish...)
+}
+
+// Query is a measured query of a suite.
+type Query struct {
+	Name string
+	// Init is the script that loads the data of the query, it runs before the
+	// query and is not measured
+	Init string
+	SQL  string
+	Args []any
+}
+
+// Suite is a list of queries measured one after another.
+type Suite struct {
+	Name    string
+	Queries []Query
+}
+
+// Select returns the suite with only the named query, the whole suite when
+// the name is empty.
+func (s Suite) Select(name string) (Suite, error) {
+	if name == "" {
+		return s, nil
+	}
+	for _, q := range s.Queries {
+		if q.Name == name {
+			return Suite{Name: s.Name, Queries: []Query{q}}, nil
+		}
+	}
+	return s, fmt.Errorf("unknown query %s of %s", name, s.Name)
+}
+
+// Result is the measurement of a query on a target.
+type Result struct {
+	Target     string
+	Query      string
+	Executions int
+	Total      time.Duration
+}
+
+// Average is the time of a single execution.
+func (r Result) Average() time.Duration {
+	if r.Executions == 0 {
+		return 0
+	}
+	return r.Total / time.Duration(r.Executions)
+}
+
+// Runner measures queries: the warm-up executions are not measured, every
+// execution has its own timeout.
+type Runner struct {
+	WarmUp     int
+	Executions int
+	Timeout    time.Duration
+}
+
+// NewRunner returns a runner with the default number of executions and timeout.
+func NewRunner() Runner {
+	return Runner{
+		WarmUp:     config.WarmUpExecutions,
+		Executions: config.TestExecutions,
+		Timeout:    config.QueryTimeout,
+	}
+}
+
+// Measure warms exec up and returns the total time of the measured
+// executions. after, when set, runs after every execution and is not measured.
+func (r Runner) Measure(ctx context.Context, exec func(context.Context) error, after func()) (time.Duration, error) {
+	run := func() (time.Duration, error) {
+		ctx := ctx
+		if r.Timeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, r.Timeout)
+			defer cancel()
+		}
+		start := time.Now()
+		err := exec(ctx)
+		elapsed := time.Since(start)
+		if after != nil {
+			after()
+		}
+		return elapsed, err
+	}
+
+	for i := 0; i < r.WarmUp; i++ {
+		if _, err := run(); err != nil {
+			return 0, err
+		}
+	}
+
+	var total time.Duration
+	for i := 0; i < r.Executions; i++ {
+		elapsed, err := run()
+		if err != nil {
+			return 0, err
+		}
+		total += elapsed
+	}
+	return total, nil
+}
+
+// RunQuery measures a query on an open database, every execution reads all
+// the rows of the query.
+func (r Runner) RunQuery(ctx context.Context, db *sql.DB, target string, q Query) (Result, error) {
+	total, err := r.Measure(ctx, func(ctx context.Context) error {
+		return ReadAll(ctx, db, q.SQL, q.Args...)
+	}, nil)
+	if err != nil {
+		return Result{}, fmt.Errorf("%s: %s: %w", target, q.Name, err)
+	}
+	return Result{Target: target, Query: q.Name, Executions: r.Executions, Total: total}, nil
+}
+
+// Run measures the queries of a suite on a target. init, when set, runs the
+// init script of a query before it.
+func (r Runner) Run(ctx context.Context, t Target, s Suite, init func(ctx context.Context, script string) error) ([]Result, error) {
+	db, err := t.Open(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	var results []Result
+	for _, q := range s.Queries {
+		if q.Init != "" && init != nil {
+			if err := init(ctx, q.Init); err != nil {
+				return results, fmt.Errorf("%s: %s: %w", t.Name, q.Init, err)
+			}
+		}
+		result, err := r.RunQuery(ctx, db, t.Name, q)
+		if err != nil {
+			return results, err
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// Queryer is implemented by both *sql.DB and *sql.Conn, so a query can run
+// either on the pool or on a pinned session with its own settings.
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// ReadAll runs a query and reads all its rows. A multi statement script is
+// read to the end, every result set of it.
+func ReadAll(ctx context.Context, db Queryer, query string, args ...any) error {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for {
+		columns, err := rows.Columns()
+		if err != nil {
+			return err
+		}
+		values := make([]any, len(columns))
+		for i := range values {
+			values[i] = new(any)
+		}
+		for rows.Next() {
+			if err := rows.Scan(values...); err != nil {
+				return err
+			}
+		}
+		if !rows.NextResultSet() {
+			break
+		}
+	}
+	return rows.Err()
+}
+
+// LogResults logs the average time of every result by target and query.
+func LogResults(results []Result) {
+	averages := make(map[string]string, len(results))
+	for _, r := range results {
+		averages[fmt.Sprintf("%s - %s", r.Target, r.Query)] = r.Average().String()
+	}
+
+	pretty, err := json.MarshalIndent(averages, "", "  ")
+	if err != nil {
+		log.Println("error:", err)
+		return
+	}
+	log.Println(string(pretty))
+}
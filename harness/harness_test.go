@@ -0,0 +1,91 @@
+package harness
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestMeasure(t *testing.T) {
+	runner := Runner{WarmUp: 2, Executions: 3, Timeout: time.Minute}
+
+	var execs, afters int
+	_, err := runner.Measure(context.Background(), func(ctx context.Context) error {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Errorf("Measure() did not set the timeout")
+		}
+		execs++
+		return nil
+	}, func() { afters++ })
+	if err != nil {
+		t.Fatalf("Measure() failed: %v", err)
+	}
+	if execs != 5 || afters != 5 {
+		t.Errorf("Measure() executed %d times and ran after %d times, expected 5", execs, afters)
+	}
+
+	execs = 0
+	failure := errors.New("failure")
+	if _, err := runner.Measure(context.Background(), func(context.Context) error {
+		execs++
+		return failure
+	}, nil); !errors.Is(err, failure) || execs != 1 {
+		t.Errorf("Measure() = %v after %d executions, expected the first error", err, execs)
+	}
+}
+
+func TestAverage(t *testing.T) {
+	tests := []struct {
+		result   Result
+		expected time.Duration
+	}{
+		{Result{Executions: 4, Total: 2 * time.Second}, 500 * time.Millisecond},
+		{Result{}, 0},
+	}
+
+	for _, test := range tests {
+		if actual := test.result.Average(); actual != test.expected {
+			t.Errorf("%+v.Average() = %s, expected %s", test.result, actual, test.expected)
+		}
+	}
+}
+
+func TestSelect(t *testing.T) {
+	suite := Suite{Name: "s", Queries: []Query{{Name: "q1"}, {Name: "q2"}}}
+
+	if s, err := suite.Select(""); err != nil || len(s.Queries) != 2 {
+		t.Errorf("Select(\"\") = %+v, %v", s, err)
+	}
+	if s, err := suite.Select("q2"); err != nil || len(s.Queries) != 1 || s.Queries[0].Name != "q2" {
+		t.Errorf("Select(q2) = %+v, %v", s, err)
+	}
+	if _, err := suite.Select("q3"); err == nil {
+		t.Errorf("Select() of an unknown query did not fail")
+	}
+}
+
+func TestInitScripts(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"init_db.sql", "q1_init.sql", "q1.sql", "notes.txt"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	files, err := InitScripts(dir)
+	if err != nil {
+		t.Fatalf("InitScripts() failed: %v", err)
+	}
+	expected := []string{"/tmp/init_db.sql", "/tmp/q1_init.sql"}
+	if len(files) != len(expected) {
+		t.Fatalf("InitScripts() = %+v, expected %v", files, expected)
+	}
+	for i, path := range expected {
+		if files[i].ContainerFilePath != path {
+			t.Errorf("InitScripts()[%d] = %s, expected %s", i, files[i].ContainerFilePath, path)
+		}
+	}
+}
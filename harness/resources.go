@@ -0,0 +1,157 @@
+package harness
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types/blkiodev"
+	"github.com/docker/docker/api/types/container"
+	"github.com/docker/go-units"
+)
+
+// Resources are the limits of every container StartContainer starts, so
+// every engine gets the same CPU, memory and I/O.
+type Resources struct {
+	CPUs   float64
+	Cpuset string
+	Memory int64
+	Shm    int64
+	IO     IoThrottle
+}
+
+// ResourceFlags registers the resource flags on a flag set, the same flags
+// for the demo runner and the per-engine tests, and returns the function that
+// parses them once the flag set is parsed.
+func ResourceFlags(fs *flag.FlagSet) func() (Resources, error) {
+	cpus := fs.Float64("cpus", 0, "CPUs every container may use, e.g. 2 or 1.5, unlimited when 0")
+	cpuset := fs.String("cpuset", "", "CPUs every container is pinned to, e.g. 0-3")
+	memory := fs.String("memory", "", "memory limit of every container, e.g. 4g, unlimited when empty")
+	shm := fs.String("shm", "", "size of /dev/shm of every container, e.g. 1g, the docker default when empty")
+	ioDevice := fs.String("io-device", "", "block device the I/O of every container is throttled on, e.g. /dev/nvme0n1")
+	ioBps := fs.String("io-bps", "", "read and write bytes per second of every container on -io-device, e.g. 100mb")
+	ioIops := fs.Uint64("io-iops", 0, "read and write operations per second of every container on -io-device")
+
+	return func() (Resources, error) {
+		r, err := ParseResources(*cpus, *cpuset, *memory, *shm)
+		if err != nil {
+			return r, err
+		}
+		r.IO, err = ParseIoThrottle(*ioDevice, *ioBps, *ioIops)
+		return r, err
+	}
+}
+
+// ParseResources parses the CPU and memory limits.
+func ParseResources(cpus float64, cpuset string, memory string, shm string) (Resources, error) {
+	r := Resources{CPUs: cpus, Cpuset: cpuset}
+	if cpus < 0 {
+		return r, fmt.Errorf("invalid number of CPUs %v", cpus)
+	}
+	var err error
+	if memory != "" {
+		if r.Memory, err = units.RAMInBytes(memory); err != nil {
+			return r, fmt.Errorf("invalid memory limit %q: %w", memory, err)
+		}
+	}
+	if shm != "" {
+		if r.Shm, err = units.RAMInBytes(shm); err != nil {
+			return r, fmt.Errorf("invalid shm size %q: %w", shm, err)
+		}
+	}
+	return r, nil
+}
+
+// Apply sets the limits on the host config of a container. The swap is
+// limited to the memory, so a container can not page its way past the limit.
+func (r Resources) Apply(hc *container.HostConfig) {
+	if r.CPUs > 0 {
+		hc.NanoCPUs = int64(r.CPUs * 1e9)
+	}
+	hc.CpusetCpus = r.Cpuset
+	if r.Memory > 0 {
+		hc.Memory = r.Memory
+		hc.MemorySwap = r.Memory
+	}
+	if r.Shm > 0 {
+		hc.ShmSize = r.Shm
+	}
+	r.IO.Apply(hc)
+}
+
+func (r Resources) String() string {
+	var limits []string
+	if r.CPUs > 0 {
+		limits = append(limits, fmt.Sprintf("cpus %s", strconv.FormatFloat(r.CPUs, 'f', -1, 64)))
+	}
+	if r.Cpuset != "" {
+		limits = append(limits, fmt.Sprintf("cpuset %s", r.Cpuset))
+	}
+	if r.Memory > 0 {
+		limits = append(limits, fmt.Sprintf("memory %s", units.BytesSize(float64(r.Memory))))
+	}
+	if r.Shm > 0 {
+		limits = append(limits, fmt.Sprintf("shm %s", units.BytesSize(float64(r.Shm))))
+	}
+	if io := r.IO.String(); io != "" {
+		limits = append(limits, io)
+	}
+	if len(limits) == 0 {
+		return "resources: unlimited"
+	}
+	return "resources: " + strings.Join(limits, ", ")
+}
+
+// IoThrottle limits the block I/O of every container on a device with the
+// cgroup block I/O controller.
+type IoThrottle struct {
+	Device string
+	Bps    uint64
+	IOps   uint64
+}
+
+// ParseIoThrottle parses the I/O limits.
+func ParseIoThrottle(device string, bps string, iops uint64) (IoThrottle, error) {
+	io := IoThrottle{Device: device, IOps: iops}
+	if bps != "" {
+		n, err := units.RAMInBytes(bps)
+		if err != nil || n <= 0 {
+			return io, fmt.Errorf("invalid I/O limit %q", bps)
+		}
+		io.Bps = uint64(n)
+	}
+	if device == "" && (io.Bps > 0 || io.IOps > 0) {
+		return io, fmt.Errorf("-io-bps and -io-iops need -io-device")
+	}
+	return io, nil
+}
+
+// Apply sets the throttling on the host config of a container.
+func (io IoThrottle) Apply(hc *container.HostConfig) {
+	throttle := func(rate uint64) []*blkiodev.ThrottleDevice {
+		return []*blkiodev.ThrottleDevice{{Path: io.Device, Rate: rate}}
+	}
+	if io.Bps > 0 {
+		hc.BlkioDeviceReadBps = throttle(io.Bps)
+		hc.BlkioDeviceWriteBps = throttle(io.Bps)
+	}
+	if io.IOps > 0 {
+		hc.BlkioDeviceReadIOps = throttle(io.IOps)
+		hc.BlkioDeviceWriteIOps = throttle(io.IOps)
+	}
+}
+
+func (io IoThrottle) String() string {
+	var limits []string
+	if io.Bps > 0 {
+		limits = append(limits, fmt.Sprintf("%s/s", units.BytesSize(float64(io.Bps))))
+	}
+	if io.IOps > 0 {
+		limits = append(limits, fmt.Sprintf("%d iops", io.IOps))
+	}
+	if len(limits) == 0 {
+		return ""
+	}
+	return fmt.Sprintf("io %s on %s", strings.Join(limits, " and "), io.Device)
+}
@@ -0,0 +1,50 @@
+package harness
+
+import (
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+)
+
+func TestResources(t *testing.T) {
+	limits, err := ParseResources(2, "0-3", "4g", "1g")
+	if err != nil {
+		t.Fatalf("ParseResources() failed: %v", err)
+	}
+	if expected := "resources: cpus 2, cpuset 0-3, memory 4GiB, shm 1GiB"; limits.String() != expected {
+		t.Errorf("String() = %q, expected %q", limits.String(), expected)
+	}
+
+	var hc container.HostConfig
+	limits.Apply(&hc)
+	if hc.NanoCPUs != 2e9 || hc.CpusetCpus != "0-3" || hc.Memory != 4<<30 || hc.MemorySwap != 4<<30 || hc.ShmSize != 1<<30 {
+		t.Errorf("Apply() = %+v", hc.Resources)
+	}
+
+	if actual := (Resources{}).String(); actual != "resources: unlimited" {
+		t.Errorf("String() of no limits = %q", actual)
+	}
+	if _, err := ParseResources(0, "", "lots", ""); err == nil {
+		t.Errorf("ParseResources() of an invalid memory limit did not fail")
+	}
+}
+
+func TestIoThrottle(t *testing.T) {
+	io, err := ParseIoThrottle("/dev/sda", "100mb", 500)
+	if err != nil {
+		t.Fatalf("ParseIoThrottle() failed: %v", err)
+	}
+	if expected := "io 100MiB/s and 500 iops on /dev/sda"; io.String() != expected {
+		t.Errorf("String() = %q, expected %q", io.String(), expected)
+	}
+
+	var hc container.HostConfig
+	io.Apply(&hc)
+	if len(hc.BlkioDeviceWriteBps) != 1 || hc.BlkioDeviceWriteBps[0].Rate != 100<<20 || len(hc.BlkioDeviceReadIOps) != 1 || hc.BlkioDeviceReadIOps[0].Path != "/dev/sda" {
+		t.Errorf("Apply() = %+v", hc.Resources)
+	}
+
+	if _, err := ParseIoThrottle("", "100mb", 0); err == nil {
+		t.Errorf("ParseIoThrottle() without a device did not fail")
+	}
+}
@@ -1,16 +1,6 @@
 use test;
 go
 
-if object_id('dbo.test_table', 'u') is not null drop table dbo.test_table;
-go
-
-create table dbo.test_table (
-    id int not null,
-    data char(100) not null,
-    status_id tinyint not null
-);
-go
-
 with id as (
     select row_number() over (order by (select 1)) as id
     from sys.all_columns as t1
@@ -2,55 +2,126 @@ package mysql
 
 import (
 	"context"
-	"database/sql"
-	"encoding/json"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
+	"strings"
 	"testing"
-	"time"
 
-	"github.com/solontsev/rdbms-performance-comparison/config"
+	"github.com/solontsev/rdbms-performance-comparison/harness"
+	"github.com/solontsev/rdbms-performance-comparison/schema"
 
+	"github.com/docker/go-connections/nat"
 	. "github.com/testcontainers/testcontainers-go"
 
 	_ "github.com/go-sql-driver/mysql"
 )
 
-var db *sql.DB // Database connection pool.
-var container Container
-
-// const defaultDbName = "master"
 const testDbName = "test_db"
-const port = 3306
+const port = "3306/tcp"
 const user = "root"
 const password = "mysql"
-const startContainer = false
 
 var dockerImages = []string{
-	"mysql:8.0.33",
+	"mysql:8.4.5",
+	"mysql:9.3.0",
+	"mariadb:11.8.2",
 }
 
-func Ping(ctx context.Context) {
-	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
-	defer cancel()
+// env configures the root password and the test database, MariaDB reads its
+// own variables.
+func env(dockerImage string) map[string]string {
+	if isMariaDb(dockerImage) {
+		return map[string]string{
+			"MARIADB_ROOT_PASSWORD": password,
+			"MARIADB_DATABASE":      testDbName,
+		}
+	}
+	return map[string]string{
+		"MYSQL_ROOT_PASSWORD": password,
+		"MYSQL_DATABASE":      testDbName,
+	}
+}
 
-	if err := db.PingContext(ctx); err != nil {
-		log.Fatalf("Unable to connect to database: %v", err)
+// client is the command line client of the image, MariaDB 11 has no mysql binary.
+func client(dockerImage string) string {
+	if isMariaDb(dockerImage) {
+		return "mariadb"
 	}
+	return "mysql"
+}
+
+// engine is the schema engine of the image.
+func engine(dockerImage string) string {
+	if isMariaDb(dockerImage) {
+		return schema.MariaDb
+	}
+	return schema.MySql
+}
+
+func isMariaDb(dockerImage string) bool {
+	return strings.HasPrefix(dockerImage, "mariadb")
+}
+
+var dbURL = func(host string, port nat.Port) string {
+	return fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", user, password, host, port.Port(), testDbName)
+}
+
+const filterById = "select count(*) from test_table as p where status_id = ?;"
+const filterByName = "select count(*) from test_table as p where status = ?;"
+
+var suite = harness.Suite{
+	Name: "q-suite",
+	Queries: []harness.Query{
+		{Name: "q0", Init: "q0_init.sql", SQL: filterById, Args: []any{1}},
+		{Name: "q1", Init: "q1_init.sql", SQL: filterById, Args: []any{1}},
+		{Name: "q2", Init: "q2_init.sql", SQL: filterByName, Args: []any{"active"}},
+		{Name: "q3", Init: "q3_init.sql", SQL: filterByName, Args: []any{"active"}},
+		{Name: "q4", Init: "q4_init.sql", SQL: filterByName, Args: []any{"active"}},
+	},
 }
 
-func ExecQuery(ctx context.Context) {
-	ctx, cancel := context.WithTimeout(ctx, 30*time.Second)
-	defer cancel()
+// the same container limits as the demo runner, e.g. go test ./mysql -args -memory=4g
+var resourcesFlag = harness.ResourceFlags(flag.CommandLine)
 
-	var result int32
-	err := db.QueryRowContext(ctx, "select count(*) from `order` as o inner join `order_detail` as od on od.order_id = o.id;").Scan(&result)
+// execScript runs a script from /tmp with the command line client of the image.
+func execScript(ctx context.Context, container *harness.Container, dockerImage string, script string) error {
+	return container.Run(ctx, client(dockerImage), "-u", user, "-p"+password, testDbName, "-e", "source "+harness.ScriptPath(script))
+}
+
+func startContainer(ctx context.Context, dockerImage string, t *testing.T) (*harness.Container, harness.Target) {
+	files, err := harness.InitScripts("./testdata")
 	if err != nil {
-		log.Fatal("unable to execute search query", err)
+		t.Fatal(err)
 	}
-	//log.Println("result = ", result)
+	resources, err := resourcesFlag()
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Logf("%s: %s", dockerImage, resources)
+
+	req := ContainerRequest{
+		Image: dockerImage,
+		Env:   env(dockerImage),
+		Files: files,
+	}
+	container, err := harness.StartContainer(ctx, req, resources, "mysql", port, dbURL)
+	if container != nil {
+		t.Cleanup(func() {
+			container.Terminate(ctx)
+		})
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := execScript(ctx, container, dockerImage, "init_db.sql"); err != nil {
+		t.Fatal(err)
+	}
+
+	return container, harness.Target{Name: dockerImage, Driver: "mysql", DSN: dbURL(container.ServerHost, container.ServerPort)}
 }
 
 func TestContainerWithWaitForSQL(t *testing.T) {
@@ -66,74 +137,61 @@ func TestContainerWithWaitForSQL(t *testing.T) {
 		stop()
 	}()
 
-	data := []struct {
-		name       string
-		initScript string
-		f          func(context.Context)
-	}{
-		{"q5", "empty.sql", ExecQuery},
-	}
-
-	result := make(map[string]string, len(data))
+	runner := harness.NewRunner()
+	var results []harness.Result
 
 	for _, dockerImage := range dockerImages {
-		var err error
-		//var dbConnectionString string
-		//container, dbConnectionString, err = startContainer(ctx, dockerImage, t)
-		//if err != nil {
-		//	t.Fatal(err)
-		//}
+		container, target := startContainer(ctx, dockerImage, t)
 
-		dbConnectionString := fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", user, password, "localhost", port, testDbName)
-
-		db, err = sql.Open("mysql", dbConnectionString)
+		db, err := target.Open(ctx)
 		if err != nil {
-			log.Fatal("Error creating connection: ", err.Error())
+			t.Fatal(err)
 		}
 
-		db.SetConnMaxLifetime(0)
-		db.SetMaxIdleConns(3)
-		db.SetMaxOpenConns(3)
-
-		Ping(ctx)
-
-		for _, d := range data {
-			//initScriptContainerPath := fmt.Sprintf("/tmp/%s", d.initScript)
-			//execResult, reader, err := container.Exec(ctx, []string{
-			//	"/opt/mssql-tools/bin/sqlcmd", "-S", "localhost", "-U", user, "-P", password, "-d", "master", "-i", initScriptContainerPath,
-			//})
-			//if err != nil {
-			//	t.Fatal(err)
-			//}
-			//log.Printf("Init script(%s) result = %d, output:\n%s\n", initScriptContainerPath, execResult, StreamToString(reader))
-
-			t.Run(d.name, func(t *testing.T) {
-				log.Printf("Starting test %s on image %s...", d.name, dockerImage)
-
-				for i := 0; i < config.WarmUpExecutions; i++ {
-					d.f(ctx)
-				}
+		for _, q := range suite.Queries {
+			// the table is generated from its schema description, the init
+			// script only loads the data
+			err := harness.CreateTable(ctx, db, engine(dockerImage), schema.TestTables[q.Name], func() error {
+				return execScript(ctx, container, dockerImage, q.Init)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
 
-				start := time.Now()
+			t.Run(q.Name, func(t *testing.T) {
+				log.Printf("Starting test %s on image %s...", q.Name, dockerImage)
 
-				for i := 0; i < config.TestExecutions; i++ {
-					d.f(ctx)
+				result, err := runner.RunQuery(ctx, db, target.Name, q)
+				if err != nil {
+					t.Fatal(err)
 				}
-
-				elapsed := time.Since(start)
-
-				key := fmt.Sprintf("%s - %s", dockerImage, d.name)
-				result[key] = fmt.Sprintf("%s", elapsed/time.Duration(config.TestExecutions))
+				results = append(results, result)
 			})
 		}
 
 		db.Close()
 	}
 
-	prettyResult, err := json.MarshalIndent(result, "", "  ")
+	harness.LogResults(results)
+}
+
+// q5 joins the order fixtures of the demo, it runs against a server that
+// already has them, e.g. one loaded by `go run ./demo setup`.
+var q5 = harness.Query{Name: "q5", SQL: "select count(*) from `order` as o inner join `order_detail` as od on od.order_id = o.id;"}
+
+func TestJoinOnFixtures(t *testing.T) {
+	ctx := context.Background()
+
+	target := harness.Target{Name: "localhost", Driver: "mysql", DSN: fmt.Sprintf("%s:%s@tcp(%s:%d)/%s", user, password, "localhost", 3306, testDbName)}
+	db, err := target.Open(ctx)
 	if err != nil {
-		log.Println("error:", err)
+		t.Skipf("%s needs a server with the order fixtures: %v", q5.Name, err)
 	}
+	defer db.Close()
 
-	log.Println(string(prettyResult))
+	result, err := harness.NewRunner().RunQuery(ctx, db, target.Name, q5)
+	if err != nil {
+		t.Fatal(err)
+	}
+	harness.LogResults([]harness.Result{result})
 }
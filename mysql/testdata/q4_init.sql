@@ -0,0 +1,11 @@
+insert into test_table (id, data, status)
+with id as (
+    select a.id + b.id * 10000 + 1 as id
+    from numbers as a
+    cross join numbers as b
+)
+select
+    id.id,
+    repeat('a', 100),
+    case when id.id % 10 = 0 then 'deleted' else 'active' end as status
+from id where id.id <= 10000000;
@@ -2,31 +2,33 @@ package postgres
 
 import (
 	"context"
-	"database/sql"
+	"flag"
 	"fmt"
 	"log"
 	"os"
 	"os/signal"
 	"testing"
-	"time"
 
-	"github.com/solontsev/rdbms-performance-comparison/config"
+	"github.com/solontsev/rdbms-performance-comparison/harness"
+	"github.com/solontsev/rdbms-performance-comparison/schema"
 
 	"github.com/docker/go-connections/nat"
 	. "github.com/testcontainers/testcontainers-go"
-	"github.com/testcontainers/testcontainers-go/wait"
 
 	_ "github.com/lib/pq"
 )
 
-var db *sql.DB // Database connection pool.
-
 const dbname = "postgres"
-const postgresImage = "postgres:15.3"
 const port = "5432/tcp"
 const user = "postgres"
 const password = "password"
 
+var dockerImages = []string{
+	"postgres:16.9",
+	"postgres:17.5",
+	"postgres:18beta1",
+}
+
 var env = map[string]string{
 	"POSTGRES_PASSWORD": password,
 	"POSTGRES_USER":     user,
@@ -37,67 +39,56 @@ var dbURL = func(host string, port nat.Port) string {
 	return fmt.Sprintf("postgres://%s:%s@%s:%s/%s?sslmode=disable", user, password, host, port.Port(), dbname)
 }
 
-func startContainer(ctx context.Context, t *testing.T) (Container, string, error) {
-	req := ContainerRequest{
-		Image:        postgresImage,
-		ExposedPorts: []string{port},
-		//Cmd:          []string{"postgres", "-c", "fsync=off"},
-		Env:        env,
-		WaitingFor: wait.ForSQL(nat.Port(port), "postgres", dbURL).WithStartupTimeout(config.ContainerStartupTimeout),
-		//WaitingFor: wait.ForSQL(nat.Port(port), "postgres", dbURL).WithStartupTimeout(config.ContainerStartupTimeout).WithQuery("SELECT 10"), // custom query
-		Files: []ContainerFile{
-			{
-				HostFilePath:      "./testdata/q1_init.sql",
-				ContainerFilePath: "/docker-entrypoint-initdb.d/q1_init.sql",
-				FileMode:          700,
-			},
-		},
-	}
-	container, err := GenericContainer(ctx, GenericContainerRequest{
-		ContainerRequest: req,
-		Started:          true,
-	})
+const filterById = "select count(*) from public.test_table as p where status_id = $1;"
+const filterByName = "select count(*) from public.test_table as p where status = $1;"
+
+var suite = harness.Suite{
+	Name: "q-suite",
+	Queries: []harness.Query{
+		{Name: "q0", Init: "q0_init.sql", SQL: filterById, Args: []any{1}},
+		{Name: "q1", Init: "q1_init.sql", SQL: filterById, Args: []any{1}},
+		{Name: "q2", Init: "q2_init.sql", SQL: filterByName, Args: []any{"active"}},
+		{Name: "q3", Init: "q3_init.sql", SQL: filterByName, Args: []any{"active"}},
+		{Name: "q4", Init: "q4_init.sql", SQL: filterByName, Args: []any{"active"}},
+	},
+}
+
+// the same container limits as the demo runner, e.g. go test ./postgres -args -memory=4g
+var resourcesFlag = harness.ResourceFlags(flag.CommandLine)
+
+// execScript runs a script from /tmp with psql and stops at the first error.
+func execScript(ctx context.Context, container *harness.Container, script string) error {
+	return container.Run(ctx, "psql", "-v", "ON_ERROR_STOP=1", "-U", user, "-d", dbname, "-f", harness.ScriptPath(script))
+}
+
+func startContainer(ctx context.Context, dockerImage string, t *testing.T) (*harness.Container, harness.Target) {
+	files, err := harness.InitScripts("./testdata")
 	if err != nil {
 		t.Fatal(err)
 	}
-
-	t.Cleanup(func() {
-		container.Terminate(ctx)
-	})
-
-	mappedPort, err := container.MappedPort(ctx, nat.Port(port))
+	resources, err := resourcesFlag()
 	if err != nil {
 		t.Fatal(err)
 	}
+	t.Logf("%s: %s", dockerImage, resources)
 
-	connectionString := fmt.Sprintf("host=%s port=%d user=%s "+
-		"password=%s dbname=%s sslmode=disable",
-		"127.0.0.1", mappedPort.Int(), user, password, dbname)
-
-	return container, connectionString, err
-}
-
-func Ping(ctx context.Context) {
-	ctx, cancel := context.WithTimeout(ctx, 1*time.Second)
-	defer cancel()
-
-	if err := db.PingContext(ctx); err != nil {
-		log.Fatalf("Unable to connect to database: %v", err)
+	req := ContainerRequest{
+		Image: dockerImage,
+		//Cmd:          []string{"postgres", "-c", "fsync=off"},
+		Env:   env,
+		Files: files,
+	}
+	container, err := harness.StartContainer(ctx, req, resources, "postgres", port, dbURL)
+	if container != nil {
+		t.Cleanup(func() {
+			container.Terminate(ctx)
+		})
 	}
-}
-
-// Query1 the database for the information requested and prints the results.
-// If the query fails exit the program with an error.
-func Query1(ctx context.Context, status_id int32) {
-	ctx, cancel := context.WithTimeout(ctx, 5*time.Second)
-	defer cancel()
-
-	var result int32
-	err := db.QueryRowContext(ctx, "select count(*) from public.test_table as p where status_id = $1;", sql.Named("status_id", status_id)).Scan(&result)
 	if err != nil {
-		log.Fatal("unable to execute search query", err)
+		t.Fatal(err)
 	}
-	log.Println("result = ", result)
+
+	return container, harness.Target{Name: dockerImage, Driver: "postgres", DSN: dbURL(container.ServerHost, container.ServerPort)}
 }
 
 func TestContainerWithWaitForSQL(t *testing.T) {
@@ -113,39 +104,40 @@ func TestContainerWithWaitForSQL(t *testing.T) {
 		stop()
 	}()
 
-	t.Run("first test", func(t *testing.T) {
-		_, dbConnectionString, err := startContainer(ctx, t)
-		if err != nil {
-			t.Fatal(err)
-		}
-
-		db, err = sql.Open("postgres", dbConnectionString)
-		if err != nil {
-			log.Fatal("Error creating connection: ", err.Error())
-		}
-		defer db.Close()
-
-		db.SetConnMaxLifetime(0)
-		db.SetMaxIdleConns(3)
-		db.SetMaxOpenConns(3)
-
-		warmUpExecutions := 5
-		testExecutions := 20
+	runner := harness.NewRunner()
+	var results []harness.Result
 
-		Ping(ctx)
+	for _, dockerImage := range dockerImages {
+		container, target := startContainer(ctx, dockerImage, t)
 
-		for i := 0; i < warmUpExecutions; i++ {
-			Query1(ctx, 1)
+		db, err := target.Open(ctx)
+		if err != nil {
+			t.Fatal(err)
 		}
 
-		start := time.Now()
-
-		for i := 0; i < testExecutions; i++ {
-			Query1(ctx, 1)
+		for _, q := range suite.Queries {
+			// the table is generated from its schema description, the init
+			// script only loads the data
+			err := harness.CreateTable(ctx, db, schema.PostgreSql, schema.TestTables[q.Name], func() error {
+				return execScript(ctx, container, q.Init)
+			})
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			t.Run(q.Name, func(t *testing.T) {
+				log.Printf("Starting test %s on image %s...", q.Name, dockerImage)
+
+				result, err := runner.RunQuery(ctx, db, target.Name, q)
+				if err != nil {
+					t.Fatal(err)
+				}
+				results = append(results, result)
+			})
 		}
 
-		elapsed := time.Since(start)
+		db.Close()
+	}
 
-		log.Printf("Avg execution time: %s", elapsed/time.Duration(testExecutions))
-	})
+	harness.LogResults(results)
 }
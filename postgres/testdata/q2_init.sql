@@ -1,11 +1,3 @@
-drop table if exists test_table;
-
-create table test_table (
-    id int not null,
-    data char(100) not null,
-    status char(7) not null
-);
-
 insert into test_table (id, data, status)
 select
     id.id,
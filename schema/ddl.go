@@ -0,0 +1,165 @@
+package schema
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DDL is the script that creates a table on one engine.
+type DDL struct {
+	// Create drops and creates the table, it runs before the data is loaded
+	Create []string
+	// Finish creates the secondary indexes, it runs after the data is loaded
+	// as that is faster than maintaining the indexes row by row
+	Finish      []string
+	Compromises []Compromise
+}
+
+// Statements returns the whole script, for a table that is filled later.
+func (d DDL) Statements() []string {
+	return append(append([]string{}, d.Create...), d.Finish...)
+}
+
+// Generate returns the DDL of a table on an engine.
+func Generate(engine string, t Table) DDL {
+	ddl := DDL{
+		Create: []string{
+			fmt.Sprintf("drop table if exists %s", QuoteIdent(engine, t.Name)),
+			CreateTable(engine, t),
+		},
+	}
+	for _, index := range t.Indexes {
+		ddl.Finish = append(ddl.Finish, CreateIndex(engine, t.Name, index))
+	}
+	ddl.Compromises = Compromises(engine, t)
+	return ddl
+}
+
+func QuoteIdent(engine string, name string) string {
+	switch engine {
+	case MsSql:
+		return "[" + name + "]"
+	case MySql, MariaDb:
+		return "`" + name + "`"
+	default:
+		return `"` + name + `"`
+	}
+}
+
+func QuoteIdents(engine string, names []string) string {
+	quoted := make([]string, len(names))
+	for i, name := range names {
+		quoted[i] = QuoteIdent(engine, name)
+	}
+	return strings.Join(quoted, ", ")
+}
+
+// ColumnType returns the type of a column on an engine.
+func ColumnType(engine string, c Column) string {
+	switch c.Type {
+	case TinyInt:
+		if engine == PostgreSql {
+			return "smallint"
+		}
+		return "tinyint"
+	case Char, Varchar:
+		return fmt.Sprintf("%s(%d)", c.Type, c.Size)
+	case Text:
+		switch engine {
+		case MsSql:
+			return "varchar(max)"
+		case MySql, MariaDb:
+			return "longtext"
+		}
+		return "text"
+	case Timestamp:
+		switch engine {
+		case MsSql:
+			return "datetime2"
+		case MySql, MariaDb:
+			return "datetime"
+		}
+		return "timestamp"
+	case Decimal:
+		return fmt.Sprintf("decimal(%d,%d)", c.Size, c.Scale)
+	default:
+		return c.Type
+	}
+}
+
+func CreateTable(engine string, t Table) string {
+	lines := make([]string, 0, len(t.Columns)+1)
+	for _, c := range t.Columns {
+		null := "not null"
+		if c.Nullable {
+			null = "null"
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %s", QuoteIdent(engine, c.Name), ColumnType(engine, c), null))
+	}
+	if len(t.PrimaryKey) > 0 {
+		clustering := ""
+		if engine == MsSql {
+			clustering = " clustered"
+			if t.Heap {
+				clustering = " nonclustered"
+			}
+		}
+		lines = append(lines, fmt.Sprintf("primary key%s (%s)", clustering, QuoteIdents(engine, t.PrimaryKey)))
+	}
+	return fmt.Sprintf("create table %s (%s)", QuoteIdent(engine, t.Name), strings.Join(lines, ", "))
+}
+
+func CreateIndex(engine string, table string, index Index) string {
+	columns := index.Columns
+	include := ""
+	if len(index.Include) > 0 {
+		if engine == MySql || engine == MariaDb {
+			// no included columns, make them part of the key to still cover the queries
+			columns = append(append([]string{}, columns...), index.Include...)
+		} else {
+			include = fmt.Sprintf(" include (%s)", QuoteIdents(engine, index.Include))
+		}
+	}
+	return fmt.Sprintf("create index %s on %s (%s)%s", index.Name, QuoteIdent(engine, table), QuoteIdents(engine, columns), include)
+}
+
+// Compromises lists where the DDL of an engine differs from the description.
+func Compromises(engine string, t Table) []Compromise {
+	var compromises []Compromise
+	add := func(column string, format string, args ...any) {
+		compromises = append(compromises, Compromise{Engine: engine, Table: t.Name, Column: column, Note: fmt.Sprintf(format, args...)})
+	}
+
+	for _, c := range t.Columns {
+		switch {
+		case c.Type == TinyInt && engine == PostgreSql:
+			add(c.Name, "no tinyint, smallint takes 2 bytes instead of 1")
+		case c.Type == Text && engine == MsSql:
+			add(c.Name, "no text, varchar(max) is stored off-row above 8000 bytes and can not be an index key")
+		case c.Type == Text && (engine == MySql || engine == MariaDb):
+			add(c.Name, "no unbounded text, longtext is stored off-page and indexed only by a prefix")
+		case c.Type == Timestamp && engine == MsSql:
+			add(c.Name, "no timestamp, datetime2 has 100ns precision instead of 1µs")
+		case c.Type == Timestamp && (engine == MySql || engine == MariaDb):
+			add(c.Name, "timestamp is datetime, whole seconds instead of 1µs")
+		}
+	}
+
+	switch engine {
+	case PostgreSql:
+		if t.Clustered() {
+			add("", "tables are heaps, rows are in primary key order only as they were loaded")
+		}
+	case MySql, MariaDb:
+		if t.Heap && len(t.PrimaryKey) > 0 {
+			add("", "InnoDB tables are always clustered by the primary key")
+		}
+		for _, index := range t.Indexes {
+			if len(index.Include) > 0 {
+				add("", "no included columns, %s has %s in the key", index.Name, strings.Join(index.Include, ", "))
+			}
+		}
+	}
+
+	return compromises
+}
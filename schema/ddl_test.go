@@ -0,0 +1,107 @@
+package schema
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestCreateTable(t *testing.T) {
+	table := Table{
+		Name:       "order",
+		Columns:    []Column{{Name: "id", Type: Int}, {Name: "note", Type: Text, Nullable: true}, {Name: "price", Type: Decimal, Size: 10, Scale: 2}},
+		PrimaryKey: []string{"id"},
+	}
+
+	data := map[string]string{
+		PostgreSql: `create table "order" ("id" int not null, "note" text null, "price" decimal(10,2) not null, primary key ("id"))`,
+		MySql:      "create table `order` (`id` int not null, `note` longtext null, `price` decimal(10,2) not null, primary key (`id`))",
+		MsSql:      "create table [order] ([id] int not null, [note] varchar(max) null, [price] decimal(10,2) not null, primary key clustered ([id]))",
+	}
+	for engine, expected := range data {
+		if actual := CreateTable(engine, table); actual != expected {
+			t.Errorf("CreateTable(%s) = %q, expected %q", engine, actual, expected)
+		}
+	}
+
+	table.Heap = true
+	if actual, expected := CreateTable(MsSql, table), "create table [order] ([id] int not null, [note] varchar(max) null, [price] decimal(10,2) not null, primary key nonclustered ([id]))"; actual != expected {
+		t.Errorf("CreateTable(mssql, heap) = %q, expected %q", actual, expected)
+	}
+}
+
+func TestCreateIndex(t *testing.T) {
+	index := Index{Name: "ix_ts", Columns: []string{"ts"}, Include: []string{"description"}}
+
+	if actual, expected := CreateIndex(MsSql, "t", index), "create index ix_ts on [t] ([ts]) include ([description])"; actual != expected {
+		t.Errorf("CreateIndex(mssql) = %q, expected %q", actual, expected)
+	}
+	if actual, expected := CreateIndex(MySql, "t", index), "create index ix_ts on `t` (`ts`, `description`)"; actual != expected {
+		t.Errorf("CreateIndex(mysql) = %q, expected %q", actual, expected)
+	}
+}
+
+func TestCompromises(t *testing.T) {
+	table := Table{
+		Name:       "t",
+		Columns:    []Column{{Name: "id", Type: Int}, {Name: "flag", Type: TinyInt}},
+		PrimaryKey: []string{"id"},
+		Indexes:    []Index{{Name: "ix_id", Columns: []string{"id"}, Include: []string{"flag"}}},
+	}
+
+	data := []struct {
+		engine   string
+		heap     bool
+		expected []string
+	}{
+		{PostgreSql, false, []string{"postgres: t.flag: no tinyint, smallint takes 2 bytes instead of 1", "postgres: t: tables are heaps, rows are in primary key order only as they were loaded"}},
+		{PostgreSql, true, []string{"postgres: t.flag: no tinyint, smallint takes 2 bytes instead of 1"}},
+		{MySql, false, []string{"mysql: t: no included columns, ix_id has flag in the key"}},
+		{MariaDb, true, []string{"mariadb: t: InnoDB tables are always clustered by the primary key", "mariadb: t: no included columns, ix_id has flag in the key"}},
+		{MsSql, true, nil},
+	}
+
+	for _, d := range data {
+		table.Heap = d.heap
+		var actual []string
+		for _, c := range Compromises(d.engine, table) {
+			actual = append(actual, c.String())
+		}
+		if !reflect.DeepEqual(actual, d.expected) {
+			t.Errorf("Compromises(%s, heap: %v) = %q, expected %q", d.engine, d.heap, actual, d.expected)
+		}
+	}
+}
+
+func TestTypeCompromises(t *testing.T) {
+	table := Table{Name: "t", Columns: []Column{{Name: "note", Type: Text}, {Name: "ts", Type: Timestamp}}, Heap: true}
+
+	data := map[string][]string{
+		PostgreSql: nil,
+		MySql: {
+			"mysql: t.note: no unbounded text, longtext is stored off-page and indexed only by a prefix",
+			"mysql: t.ts: timestamp is datetime, whole seconds instead of 1µs",
+		},
+		MsSql: {
+			"mssql: t.note: no text, varchar(max) is stored off-row above 8000 bytes and can not be an index key",
+			"mssql: t.ts: no timestamp, datetime2 has 100ns precision instead of 1µs",
+		},
+	}
+	for engine, expected := range data {
+		var actual []string
+		for _, c := range Compromises(engine, table) {
+			actual = append(actual, c.String())
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("Compromises(%s) = %q, expected %q", engine, actual, expected)
+		}
+	}
+}
+
+func TestTestTables(t *testing.T) {
+	if actual, expected := CreateTable(MsSql, TestTables["q1"]), "create table [test_table] ([id] int not null, [data] char(100) not null, [status_id] int not null)"; actual != expected {
+		t.Errorf("CreateTable(mssql, q1) = %q, expected %q", actual, expected)
+	}
+	if actual, expected := Generate(PostgreSql, TestTables["q4"]).Create[0], `drop table if exists "test_table"`; actual != expected {
+		t.Errorf("Generate(postgres, q4) drops %q, expected %q", actual, expected)
+	}
+}
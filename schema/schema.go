@@ -0,0 +1,80 @@
+// Package schema describes tables independently of the database engine and
+// generates the DDL of every engine from the same description, so the tables
+// the engines are compared on do not drift apart.
+package schema
+
+import "fmt"
+
+// engines the DDL is generated for
+const PostgreSql = "postgres"
+const MySql = "mysql"
+const MariaDb = "mariadb"
+const MsSql = "mssql"
+
+// Engines lists every engine DDL can be generated for.
+var Engines = []string{PostgreSql, MySql, MariaDb, MsSql}
+
+// column types, mapped to the closest type of every engine
+const Int = "int"
+const TinyInt = "tinyint"
+const Char = "char"
+const Varchar = "varchar"
+const Text = "text"
+const Timestamp = "timestamp"
+const Decimal = "decimal"
+
+type Column struct {
+	Name string
+	Type string
+	// Size is the length of char and varchar columns and the precision of decimal columns
+	Size     int
+	Scale    int
+	Nullable bool
+}
+
+type Index struct {
+	Name    string
+	Columns []string
+	// Include are stored in the index leaves only, on engines that support it
+	Include []string
+}
+
+// Table is a table with its keys and indexes. A table with a primary key is
+// clustered by it unless Heap is set, a table without one is always a heap.
+type Table struct {
+	Name       string
+	Columns    []Column
+	PrimaryKey []string
+	Heap       bool
+	Indexes    []Index
+}
+
+// Clustered reports whether the rows are stored in primary key order.
+func (t Table) Clustered() bool {
+	return len(t.PrimaryKey) > 0 && !t.Heap
+}
+
+// ColumnNames returns the names of the columns in table order.
+func (t Table) ColumnNames() []string {
+	names := make([]string, len(t.Columns))
+	for i, c := range t.Columns {
+		names[i] = c.Name
+	}
+	return names
+}
+
+// Compromise is a difference between the description and what an engine can
+// express, the results of that engine are not strictly comparable.
+type Compromise struct {
+	Engine string
+	Table  string
+	Column string
+	Note   string
+}
+
+func (c Compromise) String() string {
+	if c.Column == "" {
+		return fmt.Sprintf("%s: %s: %s", c.Engine, c.Table, c.Note)
+	}
+	return fmt.Sprintf("%s: %s.%s: %s", c.Engine, c.Table, c.Column, c.Note)
+}
@@ -0,0 +1,23 @@
+package schema
+
+// testTable is the table of the filter queries of the q-suite, only the type
+// of the status column changes between the scripts.
+func testTable(status Column) Table {
+	return Table{
+		Name: "test_table",
+		Columns: []Column{
+			{Name: "id", Type: Int},
+			{Name: "data", Type: Char, Size: 100},
+			status,
+		},
+	}
+}
+
+// TestTables are the tables of the q-suite init scripts, by script.
+var TestTables = map[string]Table{
+	"q0": testTable(Column{Name: "status_id", Type: TinyInt}),
+	"q1": testTable(Column{Name: "status_id", Type: Int}),
+	"q2": testTable(Column{Name: "status", Type: Char, Size: 7}),
+	"q3": testTable(Column{Name: "status", Type: Varchar, Size: 7}),
+	"q4": testTable(Column{Name: "status", Type: Text}),
+}